@@ -0,0 +1,204 @@
+package awsprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// BackendEnv is the environment variable that picks the Backend used
+// by NewClient, bolt or gob
+const BackendEnv = "AWSPRICE_BACKEND"
+
+// Backend stores the summary DB in the cache directory. GobBackend
+// keeps the whole DB in one gob file which is decoded into memory to
+// look anything up; BoltBackend keeps it in an indexed bolt file, so a
+// lookup only reads the offers it needs.
+type Backend interface {
+	// File is the name of the summary DB in the cache directory
+	File() string
+	// Save writes a DB to path, replacing any previous DB only once
+	// it's written in full
+	Save(path string, pd *PriceDB) error
+	// Load reads the whole DB at path into memory
+	Load(path string) (*PriceDB, error)
+	// Open returns a Pricer for lookups in the DB at path
+	Open(path string) (Pricer, error)
+	// LoadSpot reads just the imported spot history at path, whatever
+	// the DB's SchemaVersion, so it can be kept when the DB is rebuilt
+	LoadSpot(path string) (map[SpotKey][]SpotObservation, error)
+}
+
+// NewBackend returns the Backend with the given name, bolt or gob
+func NewBackend(name string) (Backend, error) {
+	switch strings.ToLower(name) {
+	case "bolt":
+		return BoltBackend{}, nil
+	case "gob":
+		return GobBackend{}, nil
+	}
+	return nil, fmt.Errorf("Unknown backend %s, expected bolt or gob", name)
+}
+
+// GobBackend keeps the summary DB as a single gob file, loaded in full
+type GobBackend struct{}
+
+// File is the name of the gob summary DB
+func (GobBackend) File() string {
+	return summaryDBFile
+}
+
+// Save writes the DB as a gob
+func (GobBackend) Save(path string, pd *PriceDB) error {
+	return pd.saveFile(path)
+}
+
+// Load decodes the gob at path
+func (GobBackend) Load(path string) (*PriceDB, error) {
+	return LoadPriceDBFile(path)
+}
+
+// Open decodes the gob at path; the PriceDB is its own Pricer
+func (GobBackend) Open(path string) (Pricer, error) {
+	return LoadPriceDBFile(path)
+}
+
+// LoadSpot decodes just the spot history of the gob at path
+func (GobBackend) LoadSpot(path string) (map[SpotKey][]SpotObservation, error) {
+	return loadSpotFile(path)
+}
+
+// backend returns the client's Backend, GobBackend if none is set
+func (c *Client) backend() Backend {
+	if c.Backend == nil {
+		return GobBackend{}
+	}
+	return c.Backend
+}
+
+// dbPath returns the location of the summary DB in the cache
+func (c *Client) dbPath() (string, error) {
+	if c.CacheDir == "" {
+		return "", &MissingError{What: "cache directory"}
+	}
+	return c.path(c.backend().File()), nil
+}
+
+// saveDB replaces the summary DB in the cache
+func (c *Client) saveDB(pd *PriceDB) error {
+	path, err := c.dbPath()
+	if err != nil {
+		return err
+	}
+	return c.backend().Save(path, pd)
+}
+
+// dbHint suggests how to recover when the summary DB can't be read
+func dbHint(err error) error {
+	var parse *ParseError
+	if !errors.As(err, &parse) {
+		return err
+	}
+	var schema *SchemaError
+	if errors.As(err, &schema) {
+		parse.Hint = "the pricing db is from another version of awsprice, run 'awsprice process --all' to rebuild it"
+	} else {
+		parse.Hint = "the pricing db is corrupt, run 'awsprice process --all' to rebuild it"
+	}
+	return err
+}
+
+// rebuild reprocesses the cached offer files when the summary DB
+// couldn't be read, reporting whether there was anything to rebuild it
+// from
+func (c *Client) rebuild(err error) bool {
+	var parse *ParseError
+	if !errors.As(err, &parse) || !c.anyOfferCached() {
+		return false
+	}
+	c.logf("Rebuilding the pricing db: %v\n", err)
+	if err := c.ReprocessJSON(context.Background()); err != nil {
+		c.logf("Unable to rebuild the pricing db: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// anyOfferCached reports whether there are offer files to process
+func (c *Client) anyOfferCached() bool {
+	for _, ex := range extractors {
+		if c.offerCached(ex.OfferCode()) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDB loads the whole summary DB, as it is
+func (c *Client) loadDB() (*PriceDB, error) {
+	path, err := c.dbPath()
+	if err != nil {
+		return nil, err
+	}
+	return c.backend().Load(path)
+}
+
+// LoadPriceDB loads the whole summary DB from the cache into memory. A
+// DB that can't be read, such as one saved with another SchemaVersion,
+// is rebuilt from the cached offer files if there are any.
+func (c *Client) LoadPriceDB() (*PriceDB, error) {
+	db, err := c.loadDB()
+	if err != nil && c.rebuild(err) {
+		db, err = c.loadDB()
+	}
+	if err != nil {
+		return nil, dbHint(err)
+	}
+	return db, nil
+}
+
+// loadSpot reads the spot history from a summary DB that may not load
+// in full, such as one saved with another SchemaVersion. There's none
+// to keep if there's no DB.
+func (c *Client) loadSpot() (map[SpotKey][]SpotObservation, error) {
+	path, err := c.dbPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return c.backend().LoadSpot(path)
+}
+
+// openDB opens the summary DB for lookups, as it is
+func (c *Client) openDB() (Pricer, error) {
+	path, err := c.dbPath()
+	if err != nil {
+		return nil, err
+	}
+	return c.backend().Open(path)
+}
+
+// OpenPricer opens the summary DB in the cache for lookups. With
+// BoltBackend only the offers looked up are read from disk. A DB that
+// can't be read is rebuilt as LoadPriceDB does. If there's no DB at
+// all, the EmbeddedSnapshot is used, if the binary has one.
+func (c *Client) OpenPricer() (Pricer, error) {
+	pricer, err := c.openDB()
+	if err != nil && c.rebuild(err) {
+		pricer, err = c.openDB()
+	}
+	var missing *MissingError
+	if errors.As(err, &missing) {
+		if snapshot, snapshotErr := EmbeddedSnapshot(); snapshotErr == nil {
+			return snapshot, nil
+		}
+	}
+	if err != nil {
+		return nil, dbHint(err)
+	}
+	return pricer, nil
+}
@@ -0,0 +1,482 @@
+package awsprice
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const boltDBFile = "_SummaryDB_v0.2.bolt"
+
+var (
+	// namesBucket maps each offer name to its OfferTypes, see
+	// encodeTypes
+	namesBucket = []byte("names")
+	// offersBucket maps a fragmentKey to a gob of the PriceDB holding
+	// just those offers
+	offersBucket = []byte("offers")
+	// metaBucket holds the DBHeader, under headerKey
+	metaBucket = []byte("meta")
+	headerKey  = []byte("header")
+)
+
+// BoltBackend keeps the summary DB in a bolt file, indexed by offer
+// name, so that a lookup only decodes the offers for that name
+type BoltBackend struct{}
+
+// File is the name of the bolt summary DB
+func (BoltBackend) File() string {
+	return boltDBFile
+}
+
+// Save writes the DB to a new bolt file, then moves it over path
+func (BoltBackend) Save(path string, pd *PriceDB) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	// a no-op once the rename has happened
+	defer os.Remove(tmp.Name())
+	db, err := bolt.Open(tmp.Name(), 0644, nil)
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return writeFragments(tx, pd)
+	})
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load reads every fragment of the bolt file at path into one PriceDB
+func (BoltBackend) Load(path string) (*PriceDB, error) {
+	bp, err := OpenBoltPricer(path, true)
+	if err != nil {
+		return nil, err
+	}
+	defer bp.Close()
+	pd := NewPriceDB()
+	err = bp.db.View(func(tx *bolt.Tx) error {
+		if err := readHeader(tx, &pd.Header); err != nil {
+			return err
+		}
+		err := tx.Bucket(offersBucket).ForEach(func(key, data []byte) error {
+			return gob.NewDecoder(bytes.NewReader(data)).Decode(pd)
+		})
+		if err != nil {
+			return err
+		}
+		// each fragment only knows its own type for a name
+		return tx.Bucket(namesBucket).ForEach(func(name, value []byte) error {
+			pd.OfferLookup[string(name)] = decodeTypes(value)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, &ParseError{File: path, Err: err}
+	}
+	return pd, nil
+}
+
+// LoadSpot decodes just the spot history from every fragment of the
+// bolt file at path, without checking its header
+func (BoltBackend) LoadSpot(path string) (map[SpotKey][]SpotObservation, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return nil, &ParseError{File: path, Err: err}
+	}
+	defer db.Close()
+	spot := make(map[SpotKey][]SpotObservation)
+	err = db.View(func(tx *bolt.Tx) error {
+		offers := tx.Bucket(offersBucket)
+		if offers == nil {
+			return fmt.Errorf("Missing offers bucket")
+		}
+		return offers.ForEach(func(key, data []byte) error {
+			var fields spotFields
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&fields); err != nil {
+				return err
+			}
+			for key, observations := range fields.Spot {
+				spot[key] = observations
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, &ParseError{File: path, Err: err}
+	}
+	return spot, nil
+}
+
+// Open opens the bolt file at path read-only for lookups
+func (BoltBackend) Open(path string) (Pricer, error) {
+	return OpenBoltPricer(path, true)
+}
+
+// fragmentKey is where the offers for a name are kept in the bolt file.
+// Offer types keyed by name get a fragment per name; the others, like
+// EFS, only have a handful of offers, which are kept together.
+func fragmentKey(name string, offerType OfferType) string {
+	switch offerType {
+	case EC2, RDS, OpenSearch, Redshift, MSK:
+		return offerType.String() + "/" + name
+	}
+	return offerType.String()
+}
+
+// fragments splits the DB into the parts that are looked up together,
+// by fragmentKey. Spot history and savings plan rates go with the
+// offers they discount.
+func (pd *PriceDB) fragments() map[string]*PriceDB {
+	frags := make(map[string]*PriceDB)
+	frag := func(key string) *PriceDB {
+		if f, ok := frags[key]; ok {
+			return f
+		}
+		f := NewPriceDB()
+		frags[key] = f
+		return f
+	}
+	for name, types := range pd.OfferLookup {
+		for _, offerType := range types {
+			frag(fragmentKey(name, offerType)).register(name, offerType)
+		}
+	}
+	for param, offer := range pd.EC2 {
+		frag(fragmentKey(param.Name, EC2)).EC2[param] = offer
+	}
+	for param, offer := range pd.RDS {
+		frag(fragmentKey(param.Name, RDS)).RDS[param] = offer
+	}
+	for param, offer := range pd.OpenSearch {
+		frag(fragmentKey(param.Name, OpenSearch)).OpenSearch[param] = offer
+	}
+	for param, offer := range pd.Redshift {
+		frag(fragmentKey(param.Name, Redshift)).Redshift[param] = offer
+	}
+	for param, offer := range pd.MSK {
+		frag(fragmentKey(param.Name, MSK)).MSK[param] = offer
+	}
+	for param, offer := range pd.EFS {
+		frag(fragmentKey("", EFS)).EFS[param] = offer
+	}
+	for param, offer := range pd.FSx {
+		frag(fragmentKey("", FSx)).FSx[param] = offer
+	}
+	for param, offer := range pd.Fargate {
+		frag(fragmentKey("", Fargate)).Fargate[param] = offer
+	}
+	for param, offer := range pd.EKS {
+		frag(fragmentKey("", EKS)).EKS[param] = offer
+	}
+	for key, observations := range pd.Spot {
+		frag(fragmentKey(key.InstanceType, EC2)).Spot[key] = observations
+	}
+	for key, rate := range pd.SavingsPlans {
+		switch key.Service {
+		case "ec2":
+			frag(fragmentKey(key.Usage, EC2)).SavingsPlans[key] = rate
+		case "fargate":
+			frag(fragmentKey("", Fargate)).SavingsPlans[key] = rate
+		default:
+			frag(fragmentKey("", SavingsPlan)).SavingsPlans[key] = rate
+		}
+	}
+	return frags
+}
+
+// writeFragments stores the DB's names and fragments in a new bolt file
+func writeFragments(tx *bolt.Tx, pd *PriceDB) error {
+	if err := writeHeader(tx, pd.header()); err != nil {
+		return err
+	}
+	names, err := tx.CreateBucketIfNotExists(namesBucket)
+	if err != nil {
+		return err
+	}
+	offers, err := tx.CreateBucketIfNotExists(offersBucket)
+	if err != nil {
+		return err
+	}
+	for name, types := range pd.OfferLookup {
+		if err := names.Put([]byte(name), encodeTypes(types)); err != nil {
+			return err
+		}
+	}
+	for key, frag := range pd.fragments() {
+		if err := putFragment(offers, key, frag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader stores the DB's header in the meta bucket
+func writeHeader(tx *bolt.Tx, header DBHeader) error {
+	meta, err := tx.CreateBucketIfNotExists(metaBucket)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(header); err != nil {
+		return err
+	}
+	return meta.Put(headerKey, b.Bytes())
+}
+
+// readHeader decodes the DB's header, checking its SchemaVersion. A
+// file without one is taken to be from before the header existed.
+func readHeader(tx *bolt.Tx, header *DBHeader) error {
+	var data []byte
+	if meta := tx.Bucket(metaBucket); meta != nil {
+		data = meta.Get(headerKey)
+	}
+	if data == nil {
+		return (DBHeader{}).check()
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(header); err != nil {
+		return err
+	}
+	return header.check()
+}
+
+// encodeTypes joins the types of a name for the names bucket, like
+// "0,6"
+func encodeTypes(types []OfferType) []byte {
+	values := make([]string, len(types))
+	for i, t := range types {
+		values[i] = strconv.Itoa(int(t))
+	}
+	return []byte(strings.Join(values, ","))
+}
+
+// decodeTypes splits the types of a name from the names bucket,
+// skipping any it can't read
+func decodeTypes(value []byte) []OfferType {
+	types := make([]OfferType, 0, 1)
+	for _, v := range strings.Split(string(value), ",") {
+		if t, err := strconv.Atoi(v); err == nil {
+			types = append(types, OfferType(t))
+		}
+	}
+	return types
+}
+
+func putFragment(offers *bolt.Bucket, key string, frag *PriceDB) error {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(frag); err != nil {
+		return err
+	}
+	return offers.Put([]byte(key), b.Bytes())
+}
+
+// BoltPricer looks offers up in a bolt summary DB, reading only the
+// fragments each lookup needs
+type BoltPricer struct {
+	db *bolt.DB
+}
+
+// OpenBoltPricer opens the bolt summary DB at path. A read-only pricer
+// may be shared with other processes, but can't store offers.
+func OpenBoltPricer(path string, readOnly bool) (*BoltPricer, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) && readOnly {
+		return nil, &MissingError{What: "pricing db " + path, Err: err}
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{ReadOnly: readOnly, Timeout: time.Second})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("Timed out waiting for %s, which another process is writing", path)
+	}
+	if err != nil {
+		return nil, &ParseError{File: path, Err: err}
+	}
+	if !readOnly {
+		err = db.Update(func(tx *bolt.Tx) error {
+			if tx.Bucket(metaBucket) == nil && tx.Bucket(offersBucket) == nil {
+				// a new file
+				if err := writeHeader(tx, NewPriceDB().header()); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.CreateBucketIfNotExists(namesBucket); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucketIfNotExists(offersBucket); err != nil {
+				return err
+			}
+			var header DBHeader
+			return readHeader(tx, &header)
+		})
+	} else {
+		err = db.View(func(tx *bolt.Tx) error {
+			var header DBHeader
+			if err := readHeader(tx, &header); err != nil {
+				return err
+			}
+			if tx.Bucket(namesBucket) == nil || tx.Bucket(offersBucket) == nil {
+				return fmt.Errorf("Missing names or offers bucket")
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		db.Close()
+		return nil, &ParseError{File: path, Err: err}
+	}
+	return &BoltPricer{db: db}, nil
+}
+
+// Close releases the bolt file
+func (bp *BoltPricer) Close() error {
+	return bp.db.Close()
+}
+
+// readFragment decodes the fragment at key, if there is one, into pd
+func readFragment(tx *bolt.Tx, key string, pd *PriceDB) error {
+	data := tx.Bucket(offersBucket).Get([]byte(key))
+	if data == nil {
+		return nil
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(pd)
+}
+
+// namedTypes returns the types registered for a name
+func namedTypes(tx *bolt.Tx, name string) []OfferType {
+	value := tx.Bucket(namesBucket).Get([]byte(name))
+	if value == nil {
+		return nil
+	}
+	return decodeTypes(value)
+}
+
+// load returns a PriceDB holding the offers for some names, of every
+// type registered for them
+func (bp *BoltPricer) load(names []string) (*PriceDB, error) {
+	pd := NewPriceDB()
+	err := bp.db.View(func(tx *bolt.Tx) error {
+		read := make(map[string]bool)
+		for _, name := range names {
+			for _, t := range namedTypes(tx, name) {
+				key := fragmentKey(name, t)
+				if read[key] {
+					continue
+				}
+				read[key] = true
+				if err := readFragment(tx, key, pd); err != nil {
+					return err
+				}
+			}
+		}
+		// each fragment only knows its own type for a name
+		for _, name := range names {
+			if types := namedTypes(tx, name); len(types) > 0 {
+				pd.OfferLookup[name] = types
+			}
+		}
+		return nil
+	})
+	return pd, err
+}
+
+// Lookup returns the types with offers of a name, as PriceDB.Lookup
+// does
+func (bp *BoltPricer) Lookup(name string) []OfferType {
+	var types []OfferType
+	bp.db.View(func(tx *bolt.Tx) error {
+		types = namedTypes(tx, name)
+		return nil
+	})
+	return types
+}
+
+// Get returns the offer for a query's name and attributes, as
+// PriceDB.Get does
+func (bp *BoltPricer) Get(q Query) (Offer, error) {
+	pd, err := bp.load([]string{q.Name})
+	if err != nil {
+		return nil, err
+	}
+	return pd.Get(q)
+}
+
+// Search returns the offers whose names match the query's pattern, as
+// PriceDB.Search does
+func (bp *BoltPricer) Search(q Query) ([]Offer, error) {
+	match, err := MatchName(q.Name)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	err = bp.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(namesBucket).ForEach(func(name, _ []byte) error {
+			if match(string(name)) {
+				names = append(names, string(name))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	pd, err := bp.load(names)
+	if err != nil {
+		return nil, err
+	}
+	return pd.Search(q)
+}
+
+// Find returns the offers of a type meeting every condition, as
+// PriceDB.Find does. Every offer of the type is read to do so.
+func (bp *BoltPricer) Find(offerType OfferType, conditions []Condition) ([]Offer, error) {
+	pd := NewPriceDB()
+	err := bp.db.View(func(tx *bolt.Tx) error {
+		prefix := []byte(offerType.String() + "/")
+		cursor := tx.Bucket(offersBucket).Cursor()
+		for key, data := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, data = cursor.Next() {
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(pd); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pd.Find(offerType, conditions)
+}
+
+// Store adds an offer to the fragment for its name
+func (bp *BoltPricer) Store(key OfferKey, offer Offer) error {
+	return bp.db.Update(func(tx *bolt.Tx) error {
+		fragKey := fragmentKey(key.Name, offer.Type())
+		frag := NewPriceDB()
+		if err := readFragment(tx, fragKey, frag); err != nil {
+			return err
+		}
+		if err := frag.Store(key, offer); err != nil {
+			return err
+		}
+		value := encodeTypes(withType(namedTypes(tx, key.Name), offer.Type()))
+		if err := tx.Bucket(namesBucket).Put([]byte(key.Name), value); err != nil {
+			return err
+		}
+		return putFragment(tx.Bucket(offersBucket), fragKey, frag)
+	})
+}
@@ -0,0 +1,114 @@
+package awsprice
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// backendDB has offers, spot history and savings plan rates to split
+// into fragments
+func backendDB(t *testing.T) *PriceDB {
+	db := searchDB(t)
+	if _, err := db.AddSpotHistory(strings.NewReader(spotHistory)); err != nil {
+		t.Fatal(err)
+	}
+	region, _ := NewRegion("us-west-2")
+	db.SavingsPlans[SavingsPlanKey{Plan: "compute", Term: "1yr", Payment: "no", Region: region,
+		Service: "ec2", Usage: "m5.xlarge"}] = 0.15
+	db.SavingsPlans[SavingsPlanKey{Plan: "compute", Term: "1yr", Payment: "no", Region: region,
+		Service: "fargate", Usage: "x86_64/Linux/vcpu"}] = 0.03
+	return db
+}
+
+func TestBoltBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsprice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	client := &Client{CacheDir: dir, Backend: BoltBackend{}}
+	db := backendDB(t)
+	if err := client.saveDB(db); err != nil {
+		t.Fatalf("Error saving bolt DB: %v", err)
+	}
+
+	loaded, err := client.LoadPriceDB()
+	if err != nil {
+		t.Fatalf("Error loading bolt DB: %v", err)
+	}
+	// saving stamps the header with the current schema
+	db.Header.Schema = SchemaVersion
+	if !reflect.DeepEqual(loaded, db) {
+		t.Errorf("Loaded DB differs from the one saved")
+	}
+
+	pricer, err := client.OpenPricer()
+	if err != nil {
+		t.Fatalf("Error opening bolt DB: %v", err)
+	}
+	defer pricer.Close()
+	for _, input := range []string{
+		"m5.xlarge",
+		"r5.2xlarge(region=eu-west-1)",
+		"m5.xlarge(market=spot, stat=p90)",
+		"m5.xlarge(sp=compute)",
+		"2xlarge",
+		"ec2(vcpu>=8, maxprice=0.4)",
+	} {
+		want, wantErr := ParseInput(db, input)
+		got, err := ParseInput(pricer, input)
+		if got != want || (err == nil) != (wantErr == nil) {
+			t.Errorf("%s: expected %q (%v) from bolt, got %q (%v)", input, want, wantErr, got, err)
+		}
+	}
+	var missing *MissingError
+	if _, err := pricer.Get(Query{Name: "m9.huge"}); !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingError for an unknown name, got %v", err)
+	}
+}
+
+func TestBoltPricerStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsprice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pricer, err := OpenBoltPricer(filepath.Join(dir, boltDBFile), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pricer.Close()
+	for region, price := range map[string]float64{"us-west-2": 0.192, "eu-west-1": 0.214} {
+		if err := pricer.Store(OfferKey{Name: "m5.xlarge", Attr: map[string]string{"region": region}}, EC2Offer{Price: price}); err != nil {
+			t.Fatalf("Error storing EC2 offer: %v", err)
+		}
+	}
+	offer, err := pricer.Get(Query{Name: "m5.xlarge", Attr: map[string]string{"region": "eu-west-1"}})
+	if err != nil || offer.HourlyPrice() != 0.214 {
+		t.Errorf("Expected 0.214 in eu-west-1, got %v (%v)", offer, err)
+	}
+	offer, err = pricer.Get(Query{Name: "m5.xlarge"})
+	if err != nil || offer.HourlyPrice() != 0.192 {
+		t.Errorf("Expected 0.192 in the default region, got %v (%v)", offer, err)
+	}
+}
+
+func TestOpenPricerMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsprice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, backend := range []Backend{GobBackend{}, BoltBackend{}} {
+		client := &Client{CacheDir: dir, Backend: backend}
+		var missing *MissingError
+		if _, err := client.OpenPricer(); !errors.As(err, &missing) {
+			t.Errorf("%T: expected a MissingError, got %v", backend, err)
+		}
+	}
+}
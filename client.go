@@ -0,0 +1,113 @@
+package awsprice
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+// CacheDirEnv is the environment variable that overrides the cache
+// directory
+const CacheDirEnv = "AWSPRICE_CACHE_DIR"
+
+// Client fetches offer files into a cache directory, processes them
+// into a summary DB there, and loads it back. Clients with different
+// cache directories are independent of each other.
+type Client struct {
+	// CacheDir holds the fetched offer files and the summary DB. It's
+	// created when first needed.
+	CacheDir string
+	// Endpoint is the base URL offer files are fetched from. It may be
+	// an http(s) mirror of the price list, or a file:// directory laid
+	// out the same way.
+	Endpoint string
+	// Format is the format offer files are fetched in, FormatJSON or
+	// FormatCSV. Empty means JSON.
+	Format string
+	// HTTPClient makes the requests; nil means http.DefaultClient
+	HTTPClient *http.Client
+	// Logger reports progress and skipped data; nil means the standard
+	// logger
+	Logger *log.Logger
+	// Backend stores the summary DB; nil means GobBackend
+	Backend Backend
+}
+
+// NewClient returns a Client for DefaultCacheDir and the endpoint set
+// by AWSPRICE_ENDPOINT or the config file. The summary DB is kept in
+// BoltBackend unless AWSPRICE_BACKEND says otherwise.
+func NewClient() (*Client, error) {
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := configuredEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	backend := Backend(BoltBackend{})
+	if name := os.Getenv(BackendEnv); name != "" {
+		if backend, err = NewBackend(name); err != nil {
+			return nil, err
+		}
+	}
+	return &Client{CacheDir: dir, Endpoint: endpoint, Format: FormatJSON, Backend: backend}, nil
+}
+
+// DefaultCacheDir returns $AWSPRICE_CACHE_DIR if set, otherwise
+// $XDG_CACHE_HOME/awsprice, falling back to ~/.awsprice_cache
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "awsprice"), nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("Unable to find home directory for the cache: %v", err)
+	}
+	return filepath.Join(usr.HomeDir, ".awsprice_cache"), nil
+}
+
+// path returns the location of a file in the cache
+func (c *Client) path(name string) string {
+	return filepath.Join(c.CacheDir, name)
+}
+
+// makeCacheDir creates the cache directory if it doesn't exist yet
+func (c *Client) makeCacheDir() error {
+	if c.CacheDir == "" {
+		return &MissingError{What: "cache directory"}
+	}
+	if err := os.MkdirAll(c.CacheDir, os.ModePerm); err != nil {
+		return fmt.Errorf("Unable to create cache directory: %v", err)
+	}
+	return nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) logf(format string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// offerFormat returns the format offer files are fetched in
+func (c *Client) offerFormat() string {
+	if c.Format == "" {
+		return FormatJSON
+	}
+	return c.Format
+}
@@ -0,0 +1,75 @@
+package awsprice
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setenv sets an environment variable, returning a function that
+// restores it
+func setenv(name, value string) func() {
+	old, had := os.LookupEnv(name)
+	os.Setenv(name, value)
+	return func() {
+		if had {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestDefaultCacheDir(t *testing.T) {
+	defer setenv(CacheDirEnv, "")()
+	defer setenv("XDG_CACHE_HOME", "/tmp/xdg")()
+
+	dir, err := DefaultCacheDir()
+	if err != nil || dir != filepath.Join("/tmp/xdg", "awsprice") {
+		t.Errorf("Expected the XDG cache directory, got %s (%v)", dir, err)
+	}
+
+	os.Setenv(CacheDirEnv, "/tmp/awsprice-cache")
+	dir, err = DefaultCacheDir()
+	if err != nil || dir != "/tmp/awsprice-cache" {
+		t.Errorf("Expected %s to take precedence, got %s (%v)", CacheDirEnv, dir, err)
+	}
+}
+
+func TestConfiguredEndpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsprice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setenv("XDG_CONFIG_HOME", dir)()
+	defer setenv(EndpointEnv, "")()
+
+	// no config file at all
+	if endpoint, err := configuredEndpoint(); err != nil || endpoint != DefaultEndpoint {
+		t.Errorf("Expected %s without a config file, got %s (%v)", DefaultEndpoint, endpoint, err)
+	}
+
+	path := filepath.Join(dir, "awsprice", "config.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(`{"endpoint": "http://mirror.example/"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if endpoint, err := configuredEndpoint(); err != nil || endpoint != "http://mirror.example" {
+		t.Errorf("Expected the configured endpoint, got %s (%v)", endpoint, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"endpoint": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := configuredEndpoint(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected a malformed config file to be an error naming it, got %v", err)
+	}
+	if _, err := NewClient(); err == nil {
+		t.Error("Expected NewClient to fail on a malformed config file")
+	}
+}
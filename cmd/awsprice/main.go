@@ -1,41 +1,280 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/jbarratt/awsprice"
 )
 
 func main() {
+	// an interrupted fetch stops cleanly, leaving its .part files to resume
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	if len(os.Args) == 1 {
 		fmt.Println("Call with fetch, process, or with a pricing string")
 		os.Exit(1)
-	} else if os.Args[1] == "fetch" {
-		awsprice.FetchJSON()
+	}
+	client, err := awsprice.NewClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if os.Args[1] == "fetch" {
+		flags := flag.NewFlagSet("fetch", flag.ExitOnError)
+		endpoint := flags.String("endpoint", client.Endpoint, "pricing endpoint or file:// mirror directory")
+		asOf := flags.String("as-of", "", "fetch and process the prices in effect on YYYY-MM-DD")
+		regions := flags.String("regions", "", "only fetch these regions, e.g. us-east-1,eu-west-1")
+		format := flags.String("format", awsprice.FormatJSON, "offer file format to fetch: json or csv")
+		flags.Parse(os.Args[2:])
+		if err := client.SetEndpoint(*endpoint); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if err := client.SetFormat(*format); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if *asOf != "" {
+			date, err := awsprice.ParseAsOf(*asOf)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			if err := client.FetchJSONAsOf(ctx, date); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+			}
+			if err := client.ProcessJSONAsOf(ctx, date); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+		} else if *regions != "" {
+			if err := client.FetchRegionsJSON(ctx, strings.Split(*regions, ",")); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+		} else if err := client.FetchJSON(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
 	} else if os.Args[1] == "process" {
-		awsprice.ProcessJSON()
+		var err error
+		if len(os.Args) > 2 && os.Args[2] == "--all" {
+			err = client.ReprocessJSON(ctx)
+		} else {
+			err = client.ProcessJSON(ctx)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	} else if os.Args[1] == "import-spot" {
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: awsprice import-spot <describe-spot-price-history.json>")
+			os.Exit(1)
+		}
+		if err := client.ImportSpot(os.Args[2]); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to import spot prices: %v\n", err)
+			os.Exit(1)
+		}
+	} else if os.Args[1] == "sp-commit" {
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: awsprice sp-commit '<pricing string>'")
+			os.Exit(1)
+		}
+		pricer, err := client.OpenPricer()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to load pricing db: %v\n", err)
+			os.Exit(1)
+		}
+		value, err := awsprice.RecommendCommitment(pricer, os.Args[2])
+		pricer.Close()
+		if err != nil {
+			fmt.Printf("Unable to recommend a commitment for '%s': %v\n", os.Args[2], err)
+			os.Exit(1)
+		}
+		fmt.Println(value)
+		printSnapshotDate(pricer)
+	} else if os.Args[1] == "describe" {
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: awsprice describe '<offer>'")
+			os.Exit(1)
+		}
+		pricer, err := client.OpenPricer()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to load pricing db: %v\n", err)
+			os.Exit(1)
+		}
+		out, err := awsprice.DescribeInput(pricer, os.Args[2])
+		pricer.Close()
+		if err != nil {
+			fmt.Printf("Unable to describe '%s': %v\n", os.Args[2], err)
+			os.Exit(1)
+		}
+		fmt.Print(out)
+		printSnapshotDate(pricer)
+	} else if os.Args[1] == "diff" {
+		flags := flag.NewFlagSet("diff", flag.ExitOnError)
+		region := flags.String("region", "", "only report changes in this region")
+		types := flags.String("type", "", "only report these offer types, e.g. ec2,rds")
+		format := flags.String("format", "table", "output format: table or json")
+		flags.Parse(os.Args[2:])
+		if flags.NArg() != 2 {
+			fmt.Println("Usage: awsprice diff [--region R] [--type ec2,rds] [--format table|json] <old> <new>")
+			fmt.Println("where <old> and <new> are 'current', a YYYY-MM-DD snapshot, or a DB file")
+			os.Exit(1)
+		}
+		var filter awsprice.DiffFilter
+		var err error
+		if *region != "" {
+			if filter.Region, err = awsprice.NewRegion(*region); err != nil {
+				fmt.Fprintf(os.Stderr, "%v: %s\n", err, *region)
+				os.Exit(1)
+			}
+		}
+		if filter.Types, err = awsprice.ParseOfferTypes(*types); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		oldDB, err := client.LoadSnapshot(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to load %s: %v\n", flags.Arg(0), err)
+			os.Exit(1)
+		}
+		newDB, err := client.LoadSnapshot(flags.Arg(1))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to load %s: %v\n", flags.Arg(1), err)
+			os.Exit(1)
+		}
+		changes := awsprice.DiffPriceDB(oldDB, newDB, filter)
+		switch *format {
+		case "table":
+			fmt.Print(awsprice.ChangeTable(changes))
+		case "json":
+			out, err := awsprice.ChangeJSON(changes)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(out)
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown format %s\n", *format)
+			os.Exit(1)
+		}
+	} else if os.Args[1] == "snapshot" {
+		flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+		regions := flags.String("regions", "", "only keep these regions, e.g. us-east-1,eu-west-1")
+		types := flags.String("type", "", "only keep these offer types, e.g. ec2,rds")
+		out := flags.String("o", "snapshot.gob.gz", "file to write the snapshot to")
+		flags.Parse(os.Args[2:])
+		var filter awsprice.SnapshotFilter
+		for _, name := range strings.Split(*regions, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			region, err := awsprice.NewRegion(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v: %s\n", err, name)
+				os.Exit(1)
+			}
+			filter.Regions = append(filter.Regions, region)
+		}
+		var err error
+		if filter.Types, err = awsprice.ParseOfferTypes(*types); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		priceDB, err := client.LoadPriceDB()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to load pricing db: %v\n", err)
+			os.Exit(1)
+		}
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		err = awsprice.WriteSnapshot(file, priceDB, filter)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to write snapshot: %v\n", err)
+			os.Exit(1)
+		}
 	} else if os.Args[1] == "help" {
-		fmt.Printf("fetch: fetch new pricing data\nprocess: rebuild local pricing db\nhelp: you're looking at it\nAnything else: a pricing string to interpret\n")
+		fmt.Printf("fetch [--endpoint URL] [--regions r1,r2] [--format json|csv] [--as-of YYYY-MM-DD]: fetch new (or historical) pricing data\nprocess [--all]: rebuild local pricing db from changed (or all) offers\nimport-spot <file>: add describe-spot-price-history JSON to the db\nsp-commit <pricing string>: recommend an hourly savings plan commitment\ndescribe <offer>: list everything known about an offer, e.g. c7g.xlarge\ndiff <old> <new>: report price changes between two dbs\nsnapshot [--regions r1,r2] [--type ec2,rds] [-o file]: write a compressed db to embed with 'make embedded'\nhelp: you're looking at it\n[--as-of YYYY-MM-DD] anything else: a pricing string to interpret\n")
+	} else if os.Args[1] == "--as-of" {
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: awsprice --as-of YYYY-MM-DD '<pricing string>'")
+			os.Exit(1)
+		}
+		date, err := awsprice.ParseAsOf(os.Args[2])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		pricer, err := client.LoadPriceDBAsOf(date)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "No prices as of %s, run 'awsprice fetch --as-of %s' first: %v\n", os.Args[2], os.Args[2], err)
+			os.Exit(1)
+		}
+		value, err := awsprice.ParseInput(pricer, os.Args[3])
+		if err != nil {
+			var ambiguous *awsprice.AmbiguousError
+			if errors.As(err, &ambiguous) {
+				fmt.Println(err)
+			} else {
+				fmt.Printf("Unable to find a price for '%s' as of %s\n", os.Args[3], os.Args[2])
+			}
+			os.Exit(1)
+		}
+		fmt.Println(value)
 	} else {
-		pricer, err := awsprice.LoadPriceDB()
+		pricer, err := client.OpenPricer()
 		if err != nil {
 			// just in case, try to fetch & process
-			if strings.Contains(fmt.Sprintf("%s", err), "no such file") {
-				awsprice.FetchJSON()
-				awsprice.ProcessJSON()
+			var missing *awsprice.MissingError
+			if errors.As(err, &missing) {
+				if err := client.FetchJSON(ctx); err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+				}
+				if err := client.ProcessJSON(ctx); err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+				}
 			}
-			pricer, err = awsprice.LoadPriceDB()
+			pricer, err = client.OpenPricer()
 			if err != nil {
-				panic(err)
+				fmt.Fprintf(os.Stderr, "Unable to load pricing db: %v\n", err)
+				os.Exit(1)
 			}
 		}
 		value, err := awsprice.ParseInput(pricer, os.Args[1])
+		pricer.Close()
 		if err != nil {
-			fmt.Printf("Unable to find a price for '%s'\n", os.Args[1])
+			var ambiguous *awsprice.AmbiguousError
+			if errors.As(err, &ambiguous) {
+				fmt.Println(err)
+			} else {
+				fmt.Printf("Unable to find a price for '%s'\n", os.Args[1])
+			}
 			os.Exit(1)
 		}
 		fmt.Println(value)
+		printSnapshotDate(pricer)
+	}
+}
+
+// printSnapshotDate notes when prices came from the snapshot compiled
+// into the binary, rather than the cache
+func printSnapshotDate(pricer awsprice.Pricer) {
+	if db, ok := pricer.(*awsprice.PriceDB); ok && db.Header.Embedded {
+		fmt.Printf("(embedded prices published %s)\n", db.Header.PublicationDate())
 	}
 }
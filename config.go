@@ -0,0 +1,107 @@
+package awsprice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultEndpoint is the public AWS Price List service
+const DefaultEndpoint = "https://pricing.us-east-1.amazonaws.com"
+
+// EndpointEnv is the environment variable that overrides the endpoint
+// offer files are fetched from
+const EndpointEnv = "AWSPRICE_ENDPOINT"
+
+// Config holds the settings read from the config file
+type Config struct {
+	Endpoint string `json:"endpoint"`
+}
+
+// configuredEndpoint returns the endpoint set by AWSPRICE_ENDPOINT or
+// the config file, or DefaultEndpoint if neither is. Only a missing
+// config file means no config; one that can't be read or parsed is an
+// error.
+func configuredEndpoint() (string, error) {
+	base := DefaultEndpoint
+	config, err := loadConfig()
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if config.Endpoint != "" {
+		base = config.Endpoint
+	}
+	if env := os.Getenv(EndpointEnv); env != "" {
+		base = env
+	}
+	return normalizeEndpoint(base)
+}
+
+// configPath returns $XDG_CONFIG_HOME/awsprice/config.json, falling
+// back to ~/.config
+func configPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "awsprice", "config.json"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "awsprice", "config.json"), nil
+}
+
+func loadConfig() (Config, error) {
+	var config Config
+	path, err := configPath()
+	if err != nil {
+		return config, err
+	}
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(file, &config)
+	if err != nil {
+		return config, fmt.Errorf("Unable to parse %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// normalizeEndpoint checks an endpoint is an http, https or file URL
+// and trims any trailing slash
+func normalizeEndpoint(base string) (string, error) {
+	parsed, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("Invalid endpoint %s: %v", base, err)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "file":
+	default:
+		return "", fmt.Errorf("Unsupported endpoint scheme in %s", base)
+	}
+	return strings.TrimSuffix(base, "/"), nil
+}
+
+// SetEndpoint changes the base URL offer files are fetched from. It
+// accepts http, https and file URLs.
+func (c *Client) SetEndpoint(base string) error {
+	endpoint, err := normalizeEndpoint(base)
+	if err != nil {
+		return err
+	}
+	c.Endpoint = endpoint
+	return nil
+}
+
+// localEndpoint returns the directory offer files are copied from when
+// the endpoint is a file:// URL
+func (c *Client) localEndpoint() (string, bool) {
+	if !strings.HasPrefix(c.Endpoint, "file://") {
+		return "", false
+	}
+	return strings.TrimPrefix(c.Endpoint, "file://"), true
+}
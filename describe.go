@@ -0,0 +1,39 @@
+package awsprice
+
+import (
+	"bytes"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// Describer is implemented by offers that can list their attributes in
+// full, as name and value pairs
+type Describer interface {
+	Describe() [][]string
+}
+
+// DescribeInput looks up a single offer, like 'c7g.xlarge(region=eu-west-1)',
+// and returns a table of everything known about it
+func DescribeInput(pricer Pricer, input string) (string, error) {
+	q, err := parseQuery(input)
+	if err != nil {
+		return "", err
+	}
+	offer, err := pricer.Get(q)
+	if err != nil {
+		return "", err
+	}
+	rows := [][]string{{"Name", offer.Name()}, {"Type", offer.Type().String()}}
+	if describer, ok := offer.(Describer); ok {
+		rows = append(rows, describer.Describe()...)
+	}
+	rows = append(rows, []string{"Price", offer.String()})
+
+	var b bytes.Buffer
+	writer := tablewriter.NewWriter(&b)
+	writer.SetHeader([]string{"Attribute", "Value"})
+	writer.SetAutoWrapText(false)
+	writer.AppendBulk(rows)
+	writer.Render()
+	return b.String(), nil
+}
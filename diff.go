@@ -0,0 +1,187 @@
+package awsprice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// ChangeKind says how an offer differs between two DBs
+type ChangeKind string
+
+// The kinds of PriceChange
+const (
+	Added    ChangeKind = "added"
+	Removed  ChangeKind = "removed"
+	Repriced ChangeKind = "repriced"
+)
+
+// PriceChange is a single offer that differs between two DBs
+type PriceChange struct {
+	Kind     ChangeKind `json:"kind"`
+	Type     OfferType  `json:"-"`
+	TypeName string     `json:"type"`
+	Name     string     `json:"name"`
+	Region   Region     `json:"region"`
+	// Detail distinguishes offers sharing a name, like RDS engines
+	Detail   string  `json:"detail,omitempty"`
+	OldPrice float64 `json:"oldPrice"`
+	NewPrice float64 `json:"newPrice"`
+	// Percent is the change relative to OldPrice, for re-priced offers
+	Percent float64 `json:"percent"`
+}
+
+// DiffFilter limits a diff to a region and/or offer types. Zero values
+// match everything.
+type DiffFilter struct {
+	Region Region
+	Types  []OfferType
+}
+
+func (df DiffFilter) matches(offerType OfferType, region Region) bool {
+	if df.Region != "" && df.Region != region {
+		return false
+	}
+	if len(df.Types) == 0 {
+		return true
+	}
+	for _, t := range df.Types {
+		if t == offerType {
+			return true
+		}
+	}
+	return false
+}
+
+// diffKey identifies an offer across DBs
+type diffKey struct {
+	Type   OfferType
+	Name   string
+	Region Region
+	Detail string
+}
+
+// diffPrices lists the hourly price of every EC2 and RDS offer in a DB
+func diffPrices(pd *PriceDB) map[diffKey]float64 {
+	prices := make(map[diffKey]float64)
+	for param, offer := range pd.EC2 {
+		prices[diffKey{EC2, param.Name, param.Region, ""}] = offer.HourlyPrice()
+	}
+	for param, offer := range pd.RDS {
+		prices[diffKey{RDS, param.Name, param.Region, param.DatabaseEngine + ", " + param.DeploymentOption}] = offer.HourlyPrice()
+	}
+	return prices
+}
+
+// DiffPriceDB reports the EC2 and RDS offers that were added, removed
+// or re-priced between two DBs
+func DiffPriceDB(oldDB *PriceDB, newDB *PriceDB, filter DiffFilter) []PriceChange {
+	oldPrices, newPrices := diffPrices(oldDB), diffPrices(newDB)
+	changes := make([]PriceChange, 0)
+	add := func(kind ChangeKind, key diffKey, oldPrice float64, newPrice float64) {
+		if !filter.matches(key.Type, key.Region) {
+			return
+		}
+		change := PriceChange{Kind: kind, Type: key.Type, TypeName: key.Type.String(), Name: key.Name,
+			Region: key.Region, Detail: key.Detail, OldPrice: oldPrice, NewPrice: newPrice}
+		if kind == Repriced && oldPrice != 0 {
+			change.Percent = 100 * (newPrice - oldPrice) / oldPrice
+		}
+		changes = append(changes, change)
+	}
+	for key, oldPrice := range oldPrices {
+		newPrice, ok := newPrices[key]
+		if !ok {
+			add(Removed, key, oldPrice, 0)
+		} else if newPrice != oldPrice {
+			add(Repriced, key, oldPrice, newPrice)
+		}
+	}
+	for key, newPrice := range newPrices {
+		if _, ok := oldPrices[key]; !ok {
+			add(Added, key, 0, newPrice)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.Detail < b.Detail
+	})
+	return changes
+}
+
+// ChangeTable returns a stringified table of price changes
+func ChangeTable(changes []PriceChange) string {
+	var b bytes.Buffer
+	if len(changes) == 0 {
+		return "No price changes\n"
+	}
+	writer := tablewriter.NewWriter(&b)
+	writer.SetHeader([]string{"change", "type", "name", "region", "detail", "old $/hr", "new $/hr", "%"})
+	for _, c := range changes {
+		oldPrice, newPrice, percent := "", "", ""
+		if c.Kind != Added {
+			oldPrice = fmt.Sprintf("$%0.3f", c.OldPrice)
+		}
+		if c.Kind != Removed {
+			newPrice = fmt.Sprintf("$%0.3f", c.NewPrice)
+		}
+		if c.Kind == Repriced {
+			percent = fmt.Sprintf("%+0.1f%%", c.Percent)
+		}
+		writer.Append([]string{string(c.Kind), c.TypeName, c.Name, string(c.Region), c.Detail, oldPrice, newPrice, percent})
+	}
+	writer.Render()
+	return b.String()
+}
+
+// ChangeJSON returns the price changes as a JSON array
+func ChangeJSON(changes []PriceChange) (string, error) {
+	data, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// ParseOfferTypes parses a comma separated list of offer type names,
+// like "ec2,rds". An unknown name is an error listing the known ones.
+func ParseOfferTypes(given string) ([]OfferType, error) {
+	types := make([]OfferType, 0)
+	for _, name := range strings.Split(given, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		offerType, ok := offerTypeNamed(name)
+		if !ok {
+			return nil, fmt.Errorf("Unknown offer type %s, expected one of %s", name, knownOfferTypes())
+		}
+		types = append(types, offerType)
+	}
+	return types, nil
+}
+
+// knownOfferTypes lists the offer type names ParseOfferTypes accepts
+func knownOfferTypes() string {
+	names := make([]string, 0, len(offerTypeNames))
+	for offerType := EC2; offerType <= SavingsPlan; offerType++ {
+		names = append(names, strings.ToLower(offerType.String()))
+	}
+	return strings.Join(names, ", ")
+}
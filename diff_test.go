@@ -0,0 +1,52 @@
+package awsprice
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffPriceDB(t *testing.T) {
+	oldDB, newDB := NewPriceDB(), NewPriceDB()
+	oregon := map[string]string{"region": "us-west-2"}
+	ireland := map[string]string{"region": "eu-west-1"}
+	oldDB.Store(OfferKey{Name: "m4.xlarge", Attr: oregon}, EC2Offer{Price: 0.2})
+	oldDB.Store(OfferKey{Name: "m4.xlarge", Attr: ireland}, EC2Offer{Price: 0.22})
+	oldDB.Store(OfferKey{Name: "c3.large", Attr: oregon}, EC2Offer{Price: 0.105})
+	newDB.Store(OfferKey{Name: "m4.xlarge", Attr: oregon}, EC2Offer{Price: 0.15})
+	newDB.Store(OfferKey{Name: "m4.xlarge", Attr: ireland}, EC2Offer{Price: 0.22})
+	newDB.Store(OfferKey{Name: "c7g.large", Attr: oregon}, EC2Offer{Price: 0.0725})
+	newDB.Store(OfferKey{Name: "db.t2.medium", Attr: oregon}, RDSOffer{Price: 0.136})
+
+	changes := DiffPriceDB(oldDB, newDB, DiffFilter{})
+	if len(changes) != 4 {
+		t.Fatalf("Expected 4 changes, got %+v", changes)
+	}
+	expected := []struct {
+		kind ChangeKind
+		name string
+	}{{Added, "c7g.large"}, {Removed, "c3.large"}, {Repriced, "m4.xlarge"}, {Added, "db.t2.medium"}}
+	for i, e := range expected {
+		if changes[i].Kind != e.kind || changes[i].Name != e.name {
+			t.Errorf("Change %d: expected %s %s, got %s %s", i, e.kind, e.name, changes[i].Kind, changes[i].Name)
+		}
+	}
+	if percent := changes[2].Percent; percent > -24.99 || percent < -25.01 {
+		t.Errorf("Expected -25%%, got %v", percent)
+	}
+
+	types, err := ParseOfferTypes("rds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseOfferTypes("rds,lambda"); err == nil || !strings.Contains(err.Error(), "ec2, rds") {
+		t.Errorf("Expected an unknown type to list the known ones, got %v", err)
+	}
+	changes = DiffPriceDB(oldDB, newDB, DiffFilter{Types: types})
+	if len(changes) != 1 || changes[0].Type != RDS {
+		t.Errorf("Expected only the RDS change, got %+v", changes)
+	}
+	region, _ := NewRegion("eu-west-1")
+	if changes = DiffPriceDB(oldDB, newDB, DiffFilter{Region: region}); len(changes) != 0 {
+		t.Errorf("Expected no changes in eu-west-1, got %+v", changes)
+	}
+}
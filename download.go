@@ -0,0 +1,137 @@
+package awsprice
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cavaliercoder/grab"
+)
+
+// downloadWorkers bounds how many offer files are fetched at once
+const downloadWorkers = 4
+
+// progressInterval is how often aggregate download progress is reported
+const progressInterval = 2 * time.Second
+
+// offerDownload is a single offer file to fetch into the cache
+type offerDownload struct {
+	Label    string
+	URL      string
+	Filename string
+	// PublicationDate is the date the index gives for this version, if any
+	PublicationDate string
+	// OfferCode is the offer the file must be for; empty for indexes
+	// and savings plan rates, which are only checked to be well formed
+	OfferCode string
+}
+
+// DownloadError records which offers failed to download, by label
+type DownloadError map[string]error
+
+func (de DownloadError) Error() string {
+	labels := make([]string, 0, len(de))
+	for label := range de {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	failures := make([]string, 0, len(labels))
+	for _, label := range labels {
+		failures = append(failures, fmt.Sprintf("%s: %v", label, de[label]))
+	}
+	return fmt.Sprintf("Failed to download %d offers: %s", len(de), strings.Join(failures, "; "))
+}
+
+// downloadProgress aggregates the transfers of concurrent downloads
+type downloadProgress struct {
+	mu        sync.Mutex
+	responses []*grab.Response
+}
+
+// track adds a transfer to the aggregate. It is a no-op on a nil
+// downloadProgress, so callers that don't report progress can pass nil.
+func (dp *downloadProgress) track(resp *grab.Response) {
+	if dp == nil {
+		return
+	}
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	dp.responses = append(dp.responses, resp)
+}
+
+// report logs a single line summarising all transfers so far
+func (dp *downloadProgress) report(c *Client, done int, total int) {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	var transferred, size uint64
+	for _, resp := range dp.responses {
+		transferred += resp.BytesTransferred()
+		size += resp.Size
+	}
+	percent := 0.0
+	if size > 0 {
+		percent = 100 * float64(transferred) / float64(size)
+	}
+	c.logf("Downloaded %d/%d offers, %0.1f of %0.1f MB (%0.0f%%)\n",
+		done, total, float64(transferred)/1e6, float64(size)/1e6, percent)
+}
+
+// downloadAll fetches the given offers with a pool of workers, logging
+// aggregate progress. It returns the failures by label. Once ctx
+// is cancelled, the offers not yet started fail with its error.
+func (c *Client) downloadAll(ctx context.Context, downloads []offerDownload, manifest *CacheManifest, workers int) DownloadError {
+	progress := &downloadProgress{}
+	failed := make(DownloadError)
+	var mu sync.Mutex
+	done := 0
+
+	queue := make(chan offerDownload)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dl := range queue {
+				err := ctx.Err()
+				if err == nil {
+					err = c.fetchOfferFile(ctx, dl, manifest, progress)
+				}
+				mu.Lock()
+				if err != nil {
+					failed[dl.Label] = err
+				}
+				done++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				mu.Lock()
+				count := done
+				mu.Unlock()
+				progress.report(c, count, len(downloads))
+			case <-finished:
+				return
+			}
+		}
+	}()
+
+	for _, dl := range downloads {
+		queue <- dl
+	}
+	close(queue)
+	wg.Wait()
+	close(finished)
+	progress.report(c, done, len(downloads))
+	return failed
+}
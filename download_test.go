@@ -0,0 +1,48 @@
+package awsprice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestDownloadError(t *testing.T) {
+	err := DownloadError{
+		"AmazonRDS": errors.New("timeout"),
+		"AmazonEC2": errors.New("404"),
+	}
+	expected := "Failed to download 2 offers: AmazonEC2: 404; AmazonRDS: timeout"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestDownloadAllSkipsExisting(t *testing.T) {
+	client, cleanup := testClient(t, DefaultEndpoint)
+	defer cleanup()
+
+	// files already cached at the same publication date are not re-checked
+	manifest := client.loadManifest()
+	downloads := make([]offerDownload, 0)
+	for _, code := range []string{"AmazonEC2", "AmazonRDS", "AmazonEFS"} {
+		if err := ioutil.WriteFile(client.path(code+".json"), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		manifest.update(code+".json", ManifestEntry{URL: client.Endpoint + "/unused", PublicationDate: "2023-01-01T00:00:00Z"})
+		downloads = append(downloads, offerDownload{Label: code, URL: "/unused", Filename: code + ".json",
+			PublicationDate: "2023-01-01T00:00:00Z"})
+	}
+	var progress bytes.Buffer
+	client.Logger = log.New(&progress, "", 0)
+	failed := client.downloadAll(context.Background(), downloads, manifest, 2)
+	if len(failed) != 0 {
+		t.Errorf("Expected no failures, got %v", failed)
+	}
+	if !strings.Contains(progress.String(), "Downloaded 3/3 offers") {
+		t.Errorf("Unexpected progress output: %q", progress.String())
+	}
+}
@@ -0,0 +1,93 @@
+package awsprice
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* Failures are reported with one of three error types, so that callers
+ * embedding the package can tell a flaky network from a corrupt cache
+ * from a price that simply doesn't exist. Each wraps its cause. A
+ * lookup that could mean offers of more than one type is an
+ * AmbiguousError instead.
+ */
+
+// NetworkError is a failure to fetch a file from the pricing endpoint
+type NetworkError struct {
+	URL string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("Unable to fetch %s: %v", e.URL, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is a cached offer, index or DB file that couldn't be read
+type ParseError struct {
+	File string
+	Err  error
+	// Hint suggests how to recover, if there's a way
+	Hint string
+}
+
+func (e *ParseError) Error() string {
+	if e.Hint != "" {
+		return fmt.Sprintf("Unable to parse %s: %v (%s)", e.File, e.Err, e.Hint)
+	}
+	return fmt.Sprintf("Unable to parse %s: %v", e.File, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// MissingError is data that isn't available: an offer absent from the
+// index, a file absent from the cache, or a price absent from the DB
+type MissingError struct {
+	What string
+	Err  error
+}
+
+func (e *MissingError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("No %s: %v", e.What, e.Err)
+	}
+	return fmt.Sprintf("No %s", e.What)
+}
+
+// Unwrap returns the underlying error, if any
+func (e *MissingError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaError is a summary DB saved with another SchemaVersion. It's
+// reported as the Err of a ParseError for the DB file.
+type SchemaError struct {
+	Have int
+	Want int
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("Summary DB has schema version %d, expected %d", e.Have, e.Want)
+}
+
+// AmbiguousError is a name with offers of more than one type, looked up
+// without saying which is meant
+type AmbiguousError struct {
+	Name  string
+	Types []OfferType
+}
+
+func (e *AmbiguousError) Error() string {
+	qualified := make([]string, len(e.Types))
+	for i, t := range e.Types {
+		qualified[i] = strings.ToLower(t.String()) + ":" + e.Name
+	}
+	return fmt.Sprintf("%s is ambiguous, use one of %s", e.Name, strings.Join(qualified, ", "))
+}
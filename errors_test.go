@@ -0,0 +1,52 @@
+package awsprice
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestLoadPriceDBErrors(t *testing.T) {
+	client, cleanup := testClient(t, DefaultEndpoint)
+	defer cleanup()
+
+	_, err := client.LoadPriceDB()
+	var missing *MissingError
+	if !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingError for no DB, got %v", err)
+	}
+
+	path := client.path(summaryDBFile)
+	if err := ioutil.WriteFile(path, []byte("not a gob"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.LoadPriceDB()
+	var parse *ParseError
+	if !errors.As(err, &parse) || parse.File != path {
+		t.Errorf("Expected a ParseError for %s, got %v", path, err)
+	}
+	if !strings.Contains(err.Error(), "awsprice process") {
+		t.Errorf("Expected a suggestion to re-run process, got %v", err)
+	}
+}
+
+func TestNoCacheDir(t *testing.T) {
+	client := &Client{}
+	var missing *MissingError
+	if _, err := client.LoadPriceDB(); !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingError for no cache directory, got %v", err)
+	}
+	if err := client.ProcessJSON(context.Background()); !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingError for no cache directory, got %v", err)
+	}
+}
+
+func TestGetMissing(t *testing.T) {
+	_, err := NewPriceDB().Get(Query{Name: "m4.xlarge", Attr: map[string]string{}})
+	var missing *MissingError
+	if !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingError, got %v", err)
+	}
+}
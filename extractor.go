@@ -1,137 +1,613 @@
 package awsprice
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 /* This package processes AWS pricing JSON files and compiles into a local
  * cache database
  */
 
-func simpleEC2Price(terms map[string]EC2TermItem) (float64, error) {
-	for _, term := range terms {
-		for _, dimension := range term.PriceDimensions {
-			hourly, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
-			if err == nil {
-				return hourly, nil
+// Extractor distils the cached offer files of one service into the
+// PriceDB. Registering one with registerExtractor only takes care of
+// fetching and processing its offer: a new service still needs its own
+// OfferType, a map in PriceDB, and a getter and storeIn for its offers.
+type Extractor interface {
+	// OfferCode is the offer read, like AmazonEC2
+	OfferCode() string
+	// Extract stores the offers from every file of the offer in the
+	// client's cache, stopping early if ctx is cancelled
+	Extract(ctx context.Context, c *Client, priceDB *PriceDB) error
+	// Carry copies the extracted offers from a previous DB, for when
+	// the offer files haven't changed
+	Carry(from, to *PriceDB)
+}
+
+// PricedProduct is a product from an offer file with its on-demand price
+type PricedProduct struct {
+	Product
+	Price float64
+}
+
+// productExtractor is an Extractor for services where each product
+// priced is an offer in its own right, such as an EC2 instance type
+type productExtractor struct {
+	code      string
+	offerType OfferType
+	// filter reports whether a product is one to store
+	filter func(p Product) bool
+	// key gives the name and attributes the offer is stored under
+	key func(p PricedProduct) OfferKey
+	// offer builds the offer for a product
+	offer func(p PricedProduct) (Offer, error)
+}
+
+func (pe productExtractor) OfferCode() string {
+	return pe.code
+}
+
+// Extract stores an offer for each product that passes the filter
+func (pe productExtractor) Extract(ctx context.Context, c *Client, priceDB *PriceDB) error {
+	return c.extractProducts(ctx, pe.code, pe.filter, func(products []PricedProduct) {
+		for _, p := range products {
+			offer, err := pe.offer(p)
+			if err != nil {
+				c.logf("Unable to build %s offer for SKU=%s: %v\n", pe.code, p.SKU, err)
+				continue
+			}
+			if err := priceDB.Store(pe.key(p), offer); err != nil {
+				c.logf("Unable to store %s price: %v\n", pe.code, err)
 			}
 		}
-	}
-	return 0.0, fmt.Errorf("Error getting pricing from %+v", terms)
+	})
+}
+
+func (pe productExtractor) Carry(from, to *PriceDB) {
+	to.carry(from, pe.offerType)
+}
+
+// groupExtractor is an Extractor for services whose offers combine the
+// prices of several products, like storage and throughput rates
+type groupExtractor struct {
+	code      string
+	offerType OfferType
+	filter    func(p Product) bool
+	group     func(c *Client, priceDB *PriceDB, products []PricedProduct)
+}
+
+func (ge groupExtractor) OfferCode() string {
+	return ge.code
+}
+
+func (ge groupExtractor) Extract(ctx context.Context, c *Client, priceDB *PriceDB) error {
+	return c.extractProducts(ctx, ge.code, ge.filter, func(products []PricedProduct) {
+		ge.group(c, priceDB, products)
+	})
 }
 
-func simpleRDSPrice(terms map[string]RDSTermItem) (float64, error) {
-	for _, term := range terms {
-		for _, dimension := range term.PriceDimensions {
-			hourly, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
-			if err == nil {
-				return hourly, nil
+func (ge groupExtractor) Carry(from, to *PriceDB) {
+	to.carry(from, ge.offerType)
+}
+
+// extractProducts streams each cached file of an offer, handing the
+// products that pass filter, with their prices, to use
+func (c *Client) extractProducts(ctx context.Context, code string, filter func(p Product) bool, use func(products []PricedProduct)) error {
+	keep := func(p Product) bool {
+		return p.Attr("location") != "AWS GovCloud (US)" && filter(p)
+	}
+	for _, path := range c.offerPaths(code) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		products, prices, err := streamOfferFile(path, keep)
+		if err != nil {
+			return &ParseError{File: path, Err: err}
+		}
+		priced := make([]PricedProduct, 0, len(products))
+		for _, p := range products {
+			price, ok := prices[p.SKU]
+			if !ok {
+				c.logf("No offers found for %s @ SKU=%s\n", code, p.SKU)
+				continue
 			}
+			priced = append(priced, PricedProduct{Product: p, Price: price})
 		}
+		use(priced)
 	}
-	return 0.0, fmt.Errorf("Error getting pricing from %+v", terms)
+	return nil
 }
 
-func extractEC2(priceDB *PriceDB) {
-	ec2path := filepath.Join(cacheDir, "AmazonEC2.json")
-	file, err := ioutil.ReadFile(ec2path)
-	if err != nil {
-		log.Printf("Error loading EC2 JSON: %v\n", err)
-		os.Exit(1)
-	}
-	var offerIndex EC2OfferIndex
-	err = json.Unmarshal(file, &offerIndex)
-	if err != nil {
-		log.Printf("Unable to parse EC2 offer file: %v\n", err)
-		os.Exit(1)
+// regionKey stores an offer under its name in its region
+func regionKey(name string) func(p PricedProduct) OfferKey {
+	return func(p PricedProduct) OfferKey {
+		return OfferKey{Name: p.Attr(name), Attr: map[string]string{"region": p.Attr("location")}}
 	}
+}
+
+var ec2Extractor = productExtractor{
+	code:      "AmazonEC2",
+	offerType: EC2,
 	// Right now, locked to Linux/Shared
-	for _, p := range offerIndex.Products {
-		if p.Attr.OperatingSystem != "Linux" {
+	filter: func(p Product) bool {
+		return p.Attr("operatingSystem") == "Linux" && p.Attr("tenancy") == "Shared"
+	},
+	key: regionKey("instanceType"),
+	offer: func(p PricedProduct) (Offer, error) {
+		offer := EC2Offer{Price: p.Price}
+		err := p.decodeAttr(&offer.Product)
+		offer.parseSpecs()
+		return offer, err
+	},
+}
+
+var rdsExtractor = productExtractor{
+	code:      "AmazonRDS",
+	offerType: RDS,
+	filter: func(p Product) bool {
+		return p.Attr("servicecode") != "AWSDataTransfer"
+	},
+	key: func(p PricedProduct) OfferKey {
+		return OfferKey{Name: p.Attr("instanceType"), Attr: map[string]string{"region": p.Attr("location"),
+			"engine": p.Attr("databaseEngine"), "deployment": p.Attr("deploymentOption")}}
+	},
+	offer: func(p PricedProduct) (Offer, error) {
+		offer := RDSOffer{Price: p.Price}
+		return offer, p.decodeAttr(&offer.Product)
+	},
+}
+
+var openSearchExtractor = productExtractor{
+	code:      "AmazonES",
+	offerType: OpenSearch,
+	filter: func(p Product) bool {
+		return strings.HasSuffix(p.Attr("instanceType"), ".search")
+	},
+	key: regionKey("instanceType"),
+	offer: func(p PricedProduct) (Offer, error) {
+		offer := OpenSearchOffer{Price: p.Price}
+		return offer, p.decodeAttr(&offer.Product)
+	},
+}
+
+var efsExtractor = groupExtractor{"AmazonEFS", EFS, func(p Product) bool { return true }, groupEFS}
+
+// groupEFS adds the per-region throughput charges, billed on top of
+// storage, to each storage class
+func groupEFS(c *Client, priceDB *PriceDB, products []PricedProduct) {
+	throughput := make(map[string]EFSThroughputPrices)
+	offers := make([]EFSOffer, 0)
+	for _, p := range products {
+		var attr EFSAttr
+		if err := p.decodeAttr(&attr); err != nil {
+			c.logf("Unable to read EFS SKU=%s: %v\n", p.SKU, err)
 			continue
 		}
-		if p.Attr.Tenancy != "Shared" {
-			continue
+		tp := throughput[attr.Location]
+		switch {
+		case p.ProductFamily == "Provisioned Throughput":
+			tp.Provisioned = p.Price
+		case attr.ThroughputClass == "Elastic" && attr.AccessType == "Read":
+			tp.ElasticRead = p.Price
+		case attr.ThroughputClass == "Elastic" && attr.AccessType == "Write":
+			tp.ElasticWrite = p.Price
+		case p.ProductFamily == "Storage" && attr.StorageClass != "" && attr.AccessType == "":
+			offers = append(offers, EFSOffer{Price: p.Price, Product: attr})
 		}
-		if p.Attr.Location == "AWS GovCloud (US)" {
+		throughput[attr.Location] = tp
+	}
+	for _, offer := range offers {
+		offer.Throughput = throughput[offer.Product.Location]
+		err := priceDB.Store(OfferKey{Name: "efs", Attr: map[string]string{"region": offer.Product.Location,
+			"class": offer.Product.StorageClass}}, offer)
+		if err != nil {
+			c.logf("Unable to store EFS price: %v\n", err)
 			continue
 		}
-		terms, ok := offerIndex.Terms.OnDemand[p.SKU]
-		if !ok {
-			log.Printf("No offers found for %s @ SKU=%s\n", p.Attr.InstanceType, p.SKU)
+	}
+}
+
+// fsxThroughputKey identifies the throughput capacity price that
+// applies to a group of FSx storage offers
+type fsxThroughputKey struct {
+	Location         string
+	FileSystemType   string
+	DeploymentOption string
+}
+
+var fsxExtractor = groupExtractor{"AmazonFSx", FSx, func(p Product) bool {
+	_, ok := fsxFileSystems["fsx."+strings.ToLower(p.Attr("fileSystemType"))]
+	return ok
+}, groupFSx}
+
+// groupFSx adds the throughput capacity price to each storage offer
+func groupFSx(c *Client, priceDB *PriceDB, products []PricedProduct) {
+	throughput := make(map[fsxThroughputKey]float64)
+	offers := make([]FSxOffer, 0)
+	for _, p := range products {
+		var attr FSxAttr
+		if err := p.decodeAttr(&attr); err != nil {
+			c.logf("Unable to read FSx SKU=%s: %v\n", p.SKU, err)
 			continue
 		}
-		price, err := simpleEC2Price(terms)
+		switch p.ProductFamily {
+		case "Provisioned Throughput":
+			throughput[fsxThroughputKey{attr.Location, attr.FileSystemType, attr.DeploymentOption}] = p.Price
+		case "Storage":
+			offers = append(offers, FSxOffer{Price: p.Price, Product: attr})
+		}
+	}
+	for _, offer := range offers {
+		p := offer.Product
+		offer.ThroughputPrice = throughput[fsxThroughputKey{p.Location, p.FileSystemType, p.DeploymentOption}]
+		err := priceDB.Store(OfferKey{Name: offer.Name(), Attr: map[string]string{"region": p.Location,
+			"deployment": p.DeploymentOption, "storage": p.StorageType,
+			"perunit": fsxPerUnitThroughput(p.ThroughputCapacity)}}, offer)
 		if err != nil {
-			log.Printf("Unable to get price for %s: %s\n", p.Attr.InstanceType, err)
+			c.logf("Unable to store FSx price: %v\n", err)
 			continue
 		}
+	}
+}
+
+// fargateKey identifies the set of Fargate rates for one region,
+// architecture and OS
+type fargateKey struct {
+	Location string
+	Arch     string
+	OS       string
+}
+
+var fargateExtractor = groupExtractor{"AmazonECS", Fargate, func(p Product) bool {
+	_, _, _, ok := fargateUsage(p.Attr("usagetype"))
+	return ok
+}, groupFargate}
 
-		offer := EC2Offer{Price: price, Product: p.Attr}
-		err = priceDB.StoreEC2(p.Attr.InstanceType, map[string]string{"region": p.Attr.Location}, offer)
+// groupFargate assembles the separate vCPU, memory and OS license rates
+// into one offer per region/arch/OS
+func groupFargate(c *Client, priceDB *PriceDB, products []PricedProduct) {
+	offers := make(map[fargateKey]FargateOffer)
+	for _, p := range products {
+		arch, opsys, rate, _ := fargateUsage(p.Attr("usagetype"))
+		key := fargateKey{p.Attr("location"), arch, opsys}
+		offer := offers[key]
+		offer.Location, offer.Arch, offer.OS = key.Location, key.Arch, key.OS
+		switch rate {
+		case "vcpu":
+			offer.VCPUPrice = p.Price
+		case "gb":
+			offer.GBPrice = p.Price
+		case "os":
+			offer.OSPrice = p.Price
+		}
+		offers[key] = offer
+	}
+	for _, offer := range offers {
+		err := priceDB.Store(OfferKey{Name: "fargate", Attr: map[string]string{"region": offer.Location,
+			"arch": offer.Arch, "os": offer.OS}}, offer)
 		if err != nil {
-			log.Printf("Unable to store instance price: %v\n", err)
+			c.logf("Unable to store Fargate price: %v\n", err)
 			continue
 		}
 	}
 }
 
-func extractRDS(priceDB *PriceDB) {
-	rdsPath := filepath.Join(cacheDir, "AmazonRDS.json")
-	file, err := ioutil.ReadFile(rdsPath)
-	if err != nil {
-		log.Printf("Error loading RDS JSON: %v\n", err)
-		os.Exit(1)
+var eksExtractor = groupExtractor{"AmazonEKS", EKS, func(p Product) bool {
+	usage := p.Attr("usagetype")
+	return strings.Contains(usage, "extendedSupport") || strings.HasSuffix(usage, "AmazonEKS-Hours:perCluster")
+}, groupEKS}
+
+// groupEKS combines the standard and extended support cluster rates
+func groupEKS(c *Client, priceDB *PriceDB, products []PricedProduct) {
+	offers := make(map[string]EKSOffer)
+	for _, p := range products {
+		location := p.Attr("location")
+		offer := offers[location]
+		offer.Location = location
+		if strings.Contains(p.Attr("usagetype"), "extendedSupport") {
+			offer.ExtendedPrice = p.Price
+		} else {
+			offer.Price = p.Price
+		}
+		offers[location] = offer
 	}
-	var offerIndex RDSOfferIndex
-	err = json.Unmarshal(file, &offerIndex)
-	if err != nil {
-		log.Printf("Unable to parse RDS offer file: %v\n", err)
-		os.Exit(1)
+	for _, offer := range offers {
+		err := priceDB.Store(OfferKey{Name: "eks", Attr: map[string]string{"region": offer.Location}}, offer)
+		if err != nil {
+			c.logf("Unable to store EKS price: %v\n", err)
+			continue
+		}
+	}
+}
+
+var redshiftExtractor = groupExtractor{"AmazonRedshift", Redshift, func(p Product) bool {
+	return p.ProductFamily == "Compute Instance" || p.ProductFamily == "Redshift Managed Storage"
+}, groupRedshift}
+
+// groupRedshift adds the per-region managed storage price, independent
+// of node type, to the RA3 nodes
+func groupRedshift(c *Client, priceDB *PriceDB, products []PricedProduct) {
+	storage := make(map[string]float64)
+	offers := make([]RedshiftOffer, 0)
+	for _, p := range products {
+		if p.ProductFamily == "Redshift Managed Storage" {
+			storage[p.Attr("location")] = p.Price
+			continue
+		}
+		offer := RedshiftOffer{Price: p.Price}
+		if err := p.decodeAttr(&offer.Product); err != nil {
+			c.logf("Unable to read Redshift SKU=%s: %v\n", p.SKU, err)
+			continue
+		}
+		offers = append(offers, offer)
 	}
-	for _, p := range offerIndex.Products {
-		if p.Attr.Location == "AWS GovCloud (US)" {
+	for _, offer := range offers {
+		if strings.HasPrefix(offer.Product.InstanceType, "ra3.") {
+			offer.StoragePrice = storage[offer.Product.Location]
+		}
+		err := priceDB.Store(OfferKey{Name: offer.Product.InstanceType, Attr: map[string]string{"region": offer.Product.Location}}, offer)
+		if err != nil {
+			c.logf("Unable to store Redshift node price: %v\n", err)
 			continue
 		}
-		if p.Attr.ServiceCode == "AWSDataTransfer" {
+	}
+}
+
+var mskExtractor = groupExtractor{"AmazonMSK", MSK, func(p Product) bool {
+	broker := mskBrokerType(MSKAttr{InstanceType: p.Attr("instanceType"), UsageType: p.Attr("usagetype")})
+	return strings.Contains(p.Attr("usagetype"), "Kafka.Storage") || strings.Count(broker, ".") == 2
+}, groupMSK}
+
+// groupMSK adds the per-region broker storage price, independent of
+// broker type, to each broker
+func groupMSK(c *Client, priceDB *PriceDB, products []PricedProduct) {
+	storage := make(map[string]float64)
+	offers := make([]MSKOffer, 0)
+	for _, p := range products {
+		if strings.Contains(p.Attr("usagetype"), "Kafka.Storage") {
+			storage[p.Attr("location")] = p.Price
 			continue
 		}
-		terms, ok := offerIndex.Terms.OnDemand[p.SKU]
-		if !ok {
-			log.Printf("No offers found for %s @ SKU=%s\n", p.Attr.InstanceType, p.SKU)
+		offer := MSKOffer{Price: p.Price}
+		if err := p.decodeAttr(&offer.Product); err != nil {
+			c.logf("Unable to read MSK SKU=%s: %v\n", p.SKU, err)
 			continue
 		}
-		price, err := simpleRDSPrice(terms)
+		offer.Product.InstanceType = mskBrokerType(offer.Product)
+		offers = append(offers, offer)
+	}
+	for _, offer := range offers {
+		offer.StoragePrice = storage[offer.Product.Location]
+		err := priceDB.Store(OfferKey{Name: offer.Name(), Attr: map[string]string{"region": offer.Product.Location}}, offer)
 		if err != nil {
-			log.Printf("Unable to get price for %s: %s\n", p.Attr.InstanceType, err)
+			c.logf("Unable to store MSK broker price: %v\n", err)
 			continue
 		}
+	}
+}
 
-		offer := RDSOffer{Price: price, Product: p.Attr}
-		err = priceDB.StoreRDS(p.Attr.InstanceType, map[string]string{"region": p.Attr.Location,
-			"engine": p.Attr.DatabaseEngine, "deployment": p.Attr.DeploymentOption}, offer)
+// savingsPlanExtractor reads the per-region savings plan rate files,
+// which are fetched separately from the registered offers
+type savingsPlanExtractor struct{}
+
+func (savingsPlanExtractor) OfferCode() string {
+	return savingsPlanOffer
+}
+
+func (savingsPlanExtractor) Carry(from, to *PriceDB) {
+	to.SavingsPlans = from.SavingsPlans
+}
+
+func (savingsPlanExtractor) Extract(ctx context.Context, c *Client, priceDB *PriceDB) error {
+	paths, err := filepath.Glob(c.path(savingsPlanOffer + "-*-*.json"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		c.logf("No savings plan offer files found\n")
+		return nil
+	}
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var offerIndex SavingsPlanOfferIndex
+		err = json.Unmarshal(file, &offerIndex)
+		if err != nil {
+			return &ParseError{File: path, Err: err}
+		}
+		region, err := NewRegion(offerIndex.RegionCode)
 		if err != nil {
-			log.Printf("Unable to store RDS instance price: %v\n", err)
-			log.Printf("%+v\n", p.Attr)
 			continue
 		}
+		plans := make(map[string]SavingsPlanKey)
+		for _, p := range offerIndex.Products {
+			plan, ok := savingsPlanFamilies[p.ProductFamily]
+			if !ok {
+				continue
+			}
+			plans[p.SKU] = SavingsPlanKey{Plan: plan, Term: p.Attr.PurchaseTerm,
+				Payment: savingsPlanPayments[p.Attr.PurchaseOption], Region: region}
+		}
+		for _, term := range offerIndex.Terms.SavingsPlan {
+			plan, ok := plans[term.SKU]
+			if !ok {
+				continue
+			}
+			for _, rate := range term.Rates {
+				service, usage, ok := savingsPlanUsage(rate)
+				if !ok {
+					continue
+				}
+				price, err := strconv.ParseFloat(rate.DiscountedRate.Price, 64)
+				if err != nil {
+					c.logf("Unable to get savings plan rate for %s: %s\n", rate.DiscountedUsageType, err)
+					continue
+				}
+				key := plan
+				key.Service, key.Usage = service, usage
+				priceDB.SavingsPlans[key] = price
+			}
+		}
 	}
+	return nil
+}
+
+// extractors are consulted in order by processJSON
+var extractors []Extractor
+
+// registerExtractor adds the extractor for a service, whose offer will
+// then be fetched and processed along with the rest. It doesn't give
+// the offers anywhere to be stored or looked up; see Extractor.
+func registerExtractor(ex Extractor) {
+	extractors = append(extractors, ex)
+	offerFiles = append(offerFiles, ex.OfferCode())
+}
+
+func init() {
+	for _, ex := range []Extractor{ec2Extractor, rdsExtractor, efsExtractor, fsxExtractor,
+		fargateExtractor, eksExtractor, openSearchExtractor, redshiftExtractor, mskExtractor} {
+		registerExtractor(ex)
+	}
+	extractors = append(extractors, savingsPlanExtractor{})
+}
+
+// offerPaths returns the cached files for an offer code: the full
+// <code> offer file, followed by any per-region <code>-<region> files
+// so that their newer prices take precedence
+func (c *Client) offerPaths(code string) []string {
+	paths := make([]string, 0, 1)
+	if full, ok := c.cachedOfferFile(code); ok {
+		paths = append(paths, full)
+	}
+	regions := make([]string, 0, len(codeToRegion))
+	for region := range codeToRegion {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	for _, region := range regions {
+		if regional, ok := c.cachedOfferFile(code + "-" + region); ok {
+			paths = append(paths, regional)
+		}
+	}
+	return paths
+}
+
+// cachedOfferFile returns the cached offer file named base, whether
+// fetched as JSON or CSV. If both are cached, the newer is used.
+func (c *Client) cachedOfferFile(base string) (string, bool) {
+	var newest string
+	var newestTime time.Time
+	for _, format := range []string{FormatJSON, FormatCSV} {
+		path := c.path(base + "." + format)
+		info, err := os.Stat(path)
+		if err == nil && (newest == "" || info.ModTime().After(newestTime)) {
+			newest, newestTime = path, info.ModTime()
+		}
+	}
+	return newest, newest != ""
+}
+
+// offerCached reports whether any offer files for an offer code are in
+// the cache. Its region and version indexes don't count.
+func (c *Client) offerCached(code string) bool {
+	return len(c.offerPaths(code)) > 0
+}
+
+// verifyCached checks an offer's cached files haven't changed since
+// they were fetched. A file that has is forgotten, so that the next
+// fetch replaces it.
+func (c *Client) verifyCached(manifest *CacheManifest, code string) error {
+	for _, name := range manifest.offerFileNames(code) {
+		if err := manifest.verify(name); err != nil {
+			manifest.forget(name)
+			if err := manifest.save(); err != nil {
+				c.logf("Unable to save cache manifest: %v\n", err)
+			}
+			return &ParseError{File: name, Err: err, Hint: "run 'awsprice fetch' to download it again"}
+		}
+	}
+	return nil
 }
 
 // ProcessJSON does the top level dispatching of processing all the AWS
-// pricing JSON files and distilling them.
-func ProcessJSON() {
+// pricing JSON files and distilling them. Offers whose files haven't
+// changed since they were last processed are copied from the existing
+// DB rather than extracted again.
+func (c *Client) ProcessJSON(ctx context.Context) error {
+	return c.processJSON(ctx, false)
+}
+
+// ReprocessJSON rebuilds the whole DB from the cached JSON files,
+// whether or not they have changed.
+func (c *Client) ReprocessJSON(ctx context.Context) error {
+	return c.processJSON(ctx, true)
+}
+
+func (c *Client) processJSON(ctx context.Context, all bool) error {
+	if err := c.makeCacheDir(); err != nil {
+		return err
+	}
+	manifest := c.loadManifest()
 	priceDB := NewPriceDB()
-	extractEC2(priceDB)
-	extractRDS(priceDB)
-	err := priceDB.save()
+	priceDB.Header = DBHeader{Published: make(map[string]string), Built: time.Now().UTC()}
+	// spot history is imported rather than extracted, so keep it,
+	// even from a DB that can't otherwise be read
+	oldDB, err := c.loadDB()
 	if err != nil {
-		log.Printf("Unable to save summary DB: %s\n", err)
+		all = true
+		spot, err := c.loadSpot()
+		if err != nil {
+			c.logf("Unable to keep the imported spot history, run 'awsprice import-spot' again: %v\n", err)
+		} else if spot != nil {
+			priceDB.Spot = spot
+		}
+	} else {
+		priceDB.Spot = oldDB.Spot
+	}
+	// only the offers extracted this time are newly processed
+	extracted := make([]Extractor, 0, len(extractors))
+	for _, ex := range extractors {
+		if !c.offerCached(ex.OfferCode()) {
+			c.logf("No cached %s offer, skipping\n", ex.OfferCode())
+			continue
+		}
+		if date := manifest.publicationDate(ex.OfferCode()); date != "" {
+			priceDB.Header.Published[ex.OfferCode()] = date
+		}
+		if !all && manifest.processed(ex.OfferCode()) {
+			ex.Carry(oldDB, priceDB)
+			continue
+		}
+		if err := c.verifyCached(manifest, ex.OfferCode()); err != nil {
+			return err
+		}
+		if err := ex.Extract(ctx, c, priceDB); err != nil {
+			return err
+		}
+		extracted = append(extracted, ex)
+	}
+	err = c.saveDB(priceDB)
+	if err != nil {
+		return fmt.Errorf("Unable to save summary DB: %v", err)
+	}
+	for _, ex := range extracted {
+		manifest.markProcessed(ex.OfferCode())
+	}
+	if err := manifest.save(); err != nil {
+		c.logf("Unable to save cache manifest: %v\n", err)
 	}
+	return nil
 }
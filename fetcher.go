@@ -1,25 +1,22 @@
 package awsprice
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"net/http"
 	"os"
-	"os/user"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/cavaliercoder/grab"
 )
 
-const uriBase = "https://pricing.us-east-1.amazonaws.com"
 const offerPath = "/offers/v1.0/aws/index.json"
-
-var cacheDir string
-
-func init() {
-	cacheDir = makeCacheDir()
-}
+const userAgent = "AWS Price Grammar Bot"
 
 // OfferIndex contains the top level information about the various Offers
 // aka Amazon Service families
@@ -35,82 +32,361 @@ type JSONOffer struct {
 	OfferCode         string `json:"offerCode"`
 	VersionIndexURL   string `json:"versionIndexUrl"`
 	CurrentVersionURL string `json:"currentVersionUrl"`
+	// CurrentRegionIndexURL lists a smaller offer file per region
+	CurrentRegionIndexURL string `json:"currentRegionIndexUrl"`
+	// Savings plan offers link a per-region index instead
+	CurrentSavingsPlanIndexURL string `json:"currentSavingsPlanIndexUrl"`
+}
+
+// RegionIndex lists the per-region files of an offer, as linked from
+// currentRegionIndexUrl
+type RegionIndex struct {
+	FormatVersion   string                 `json:"formatVersion"`
+	Disclaimer      string                 `json:"disclaimer"`
+	PublicationDate string                 `json:"publicationDate"`
+	Regions         map[string]RegionEntry `json:"regions"`
+}
+
+// RegionEntry identifies the offer file for one region
+type RegionEntry struct {
+	RegionCode        string `json:"regionCode"`
+	CurrentVersionURL string `json:"currentVersionUrl"`
+}
+
+// regionalDownloads fetches an offer's region index and returns the
+// downloads of the given regions' offer files
+func (c *Client) regionalDownloads(ctx context.Context, offer JSONOffer, regions []string, manifest *CacheManifest) ([]offerDownload, error) {
+	indexName := offer.OfferCode + "-regions.json"
+	err := c.fetchOfferFile(ctx, offerDownload{Label: indexName, URL: offer.CurrentRegionIndexURL, Filename: indexName}, manifest, nil)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ioutil.ReadFile(c.path(indexName))
+	if err != nil {
+		return nil, err
+	}
+	var regionIndex RegionIndex
+	err = json.Unmarshal(file, &regionIndex)
+	if err != nil {
+		return nil, &ParseError{File: indexName, Err: err}
+	}
+	downloads := make([]offerDownload, 0, len(regions))
+	for _, region := range regions {
+		entry, ok := regionIndex.Regions[region]
+		if !ok {
+			return nil, &MissingError{What: fmt.Sprintf("%s offer for region %s", offer.OfferCode, region)}
+		}
+		name := offer.OfferCode + "-" + region
+		downloads = append(downloads, offerDownload{Label: name, URL: formatURL(entry.CurrentVersionURL, c.offerFormat()),
+			Filename: name + "." + c.offerFormat(), PublicationDate: regionIndex.PublicationDate, OfferCode: offer.OfferCode})
+	}
+	return downloads, nil
+}
+
+// wantRegion reports whether a region code is among those selected.
+// No selection means every region.
+func wantRegion(regions []string, code string) bool {
+	if len(regions) == 0 {
+		return true
+	}
+	for _, region := range regions {
+		if region == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Offer file formats. AWS publishes every offer file as both.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// SetFormat sets whether offer files are fetched as JSON or CSV.
+// Indexes and savings plan rates are always fetched as JSON.
+func (c *Client) SetFormat(format string) error {
+	if format != FormatJSON && format != FormatCSV {
+		return fmt.Errorf("Unknown offer format %s, expected json or csv", format)
+	}
+	c.Format = format
+	return nil
 }
 
-func makeCacheDir() string {
-	usr, err := user.Current()
+// formatURL points an offer file URL at the given format
+func formatURL(url string, format string) string {
+	return strings.TrimSuffix(url, ".json") + "." + format
+}
+
+// offerFiles are the offer codes downloaded by FetchJSON, each saved
+// to <code>.json (or .csv) in the cache. See registerExtractor.
+var offerFiles []string
+
+// savingsPlanOffer is the offer code holding Compute and EC2 Instance
+// Savings Plans rates
+const savingsPlanOffer = "AWSComputeSavingsPlan"
+
+// savingsPlanDownloads fetches the savings plan region index and
+// returns the rate file downloads for each selected region we know about
+func (c *Client) savingsPlanDownloads(ctx context.Context, offer JSONOffer, regions []string, manifest *CacheManifest) ([]offerDownload, error) {
+	if offer.CurrentSavingsPlanIndexURL == "" {
+		return nil, &MissingError{What: savingsPlanOffer + " offer in the offer index"}
+	}
+	indexName := offer.OfferCode + "-index.json"
+	err := c.fetchOfferFile(ctx, offerDownload{Label: indexName, URL: offer.CurrentSavingsPlanIndexURL, Filename: indexName}, manifest, nil)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ioutil.ReadFile(c.path(indexName))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	cachedir := filepath.Join(usr.HomeDir, ".awsprice_cache")
-	err = os.MkdirAll(cachedir, os.ModePerm)
+	var regionIndex SavingsPlanRegionIndex
+	err = json.Unmarshal(file, &regionIndex)
 	if err != nil {
-		log.Fatalf("Unable to create cache directory")
+		return nil, &ParseError{File: indexName, Err: err}
+	}
+	downloads := make([]offerDownload, 0, len(regionIndex.Regions))
+	for _, region := range regionIndex.Regions {
+		if _, ok := codeToRegion[region.RegionCode]; !ok || !wantRegion(regions, region.RegionCode) {
+			continue
+		}
+		name := offer.OfferCode + "-" + region.RegionCode
+		downloads = append(downloads, offerDownload{Label: name, URL: region.VersionURL,
+			Filename: name + ".json", PublicationDate: regionIndex.PublicationDate})
 	}
-	return cachedir
+	return downloads, nil
 }
 
-func updateOfferJSON() {
-	err := fetchOfferFile(offerPath, "offer.json")
+func (c *Client) updateOfferJSON(ctx context.Context, manifest *CacheManifest) error {
+	return c.fetchOfferFile(ctx, offerDownload{Label: "index", URL: offerPath, Filename: "offer.json"}, manifest, nil)
+}
+
+// readOfferIndex reads the offer index fetched by updateOfferJSON
+func (c *Client) readOfferIndex() (OfferIndex, error) {
+	var offerIndex OfferIndex
+	file, err := ioutil.ReadFile(c.path("offer.json"))
+	if err != nil {
+		return offerIndex, &MissingError{What: "offer index", Err: err}
+	}
+	if err := json.Unmarshal(file, &offerIndex); err != nil {
+		return offerIndex, &ParseError{File: "offer.json", Err: err}
+	}
+	return offerIndex, nil
+}
+
+// checkFreshness makes a conditional HEAD request for a cached file.
+// It reports whether the server copy is unchanged, along with the
+// server's current ETag and Last-Modified.
+func (c *Client) checkFreshness(ctx context.Context, url string, entry ManifestEntry, cached bool) (bool, string, string, error) {
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return false, "", "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", userAgent)
+	if cached && entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if cached && entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
-		panic(err)
+		return false, "", "", &NetworkError{URL: url, Err: err}
 	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return true, entry.ETag, entry.LastModified, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, "", "", &NetworkError{URL: url, Err: fmt.Errorf("Unexpected status %s", resp.Status)}
+	}
+	return false, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"), nil
 }
 
-func fetchOfferFile(relativeURL string, outputName string) error {
+// fetchOfferFile downloads an offer file into the cache if it has
+// changed. An offer whose URL and publication date match the manifest
+// is skipped outright; otherwise a conditional request decides. The
+// download goes to a .part file first, so an interrupted transfer is
+// resumed by the next call rather than mistaken for a complete one, and
+// is only moved into place once verifyOfferFile accepts it.
+// If progress is non-nil the transfer is reported to it. Cancelling ctx
+// abandons the transfer, leaving the .part file to resume.
+func (c *Client) fetchOfferFile(ctx context.Context, dl offerDownload, manifest *CacheManifest, progress *downloadProgress) error {
+	filename := c.path(dl.Filename)
+	url := c.Endpoint + dl.URL
+	entry, cached := manifest.entry(dl.Filename)
+	if cached && dl.PublicationDate != "" && entry.URL == url && entry.PublicationDate == dl.PublicationDate {
+		return nil
+	}
+	if dir, ok := c.localEndpoint(); ok {
+		return c.copyOfferFile(filepath.Join(dir, filepath.FromSlash(dl.URL)), dl, manifest)
+	}
+	unchanged, etag, lastModified, err := c.checkFreshness(ctx, url, entry, cached)
+	if err != nil {
+		return err
+	}
+	if unchanged {
+		entry.URL, entry.PublicationDate = url, dl.PublicationDate
+		manifest.update(dl.Filename, entry)
+		return nil
+	}
+
+	partial := filename + ".part"
+	// a partial download of an older version can't be resumed
+	if entry.PartETag != etag {
+		os.Remove(partial)
+	}
+	entry.PartETag = etag
+	manifest.update(dl.Filename, entry)
+	// saved now, so a run that is killed mid-transfer can still resume
+	if err := manifest.save(); err != nil {
+		return err
+	}
+
 	client := grab.NewClient()
-	client.UserAgent = "AWS Price Grammar Bot"
-	url := uriBase + relativeURL
-	fmt.Printf("Downloading %s...\n", url)
+	client.UserAgent = userAgent
+	client.HTTPClient = c.httpClient()
+	c.logf("Downloading %s...\n", url)
 	req, err := grab.NewRequest(url)
 	if err != nil {
 		return err
 	}
-	req.SkipExisting = true
-	req.Filename = filepath.Join(cacheDir, outputName)
-	resp, err := client.Do(req)
+	req.Filename = partial
+	req.HTTPRequest = req.HTTPRequest.WithContext(ctx)
+	resp := <-client.DoAsync(req)
+	progress.track(resp)
+	// cancelling ctx aborts the transfer, which then closes Done
+	<-resp.Done
+	if resp.Error != nil {
+		return &NetworkError{URL: url, Err: resp.Error}
+	}
+	size, sum, err := verifyOfferFile(partial, dl)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Issue downloading %s: %v\n", url, err)
-		// if file exists anyway, move on
-		if _, err := os.Stat(resp.Filename); err != nil {
-			return err
-		}
+		// start afresh next time rather than resume a bad file
+		os.Remove(partial)
+		return &ParseError{File: dl.Filename, Err: err}
+	}
+	if err := os.Rename(partial, filename); err != nil {
+		return err
 	}
-	fmt.Printf("Downloaded to %s\n", resp.Filename)
+	manifest.update(dl.Filename, ManifestEntry{URL: url, PublicationDate: dl.PublicationDate,
+		ETag: etag, LastModified: lastModified, Fetched: time.Now(), Size: size, SHA256: sum})
+	c.logf("Downloaded to %s\n", filename)
 	return nil
 }
 
-func processOfferJSON() {
-
-	file, err := ioutil.ReadFile(filepath.Join(cacheDir, "offer.json"))
+// copyOfferFile is fetchOfferFile for a file:// endpoint. The source
+// file's modification time stands in for Last-Modified.
+func (c *Client) copyOfferFile(source string, dl offerDownload, manifest *CacheManifest) error {
+	filename := c.path(dl.Filename)
+	info, err := os.Stat(source)
 	if err != nil {
-		log.Printf("Error loading offer: %v\n", err)
-		os.Exit(1)
+		return &NetworkError{URL: "file://" + source, Err: err}
 	}
-	var offerIndex OfferIndex
-	err = json.Unmarshal(file, &offerIndex)
+	lastModified := info.ModTime().UTC().Format(http.TimeFormat)
+	entry, cached := manifest.entry(dl.Filename)
+	if cached && entry.LastModified == lastModified {
+		entry.URL, entry.PublicationDate = "file://"+source, dl.PublicationDate
+		manifest.update(dl.Filename, entry)
+		return nil
+	}
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	partial := filename + ".part"
+	out, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return &NetworkError{URL: "file://" + source, Err: err}
+	}
+	size, sum, err := verifyOfferFile(partial, dl)
 	if err != nil {
-		log.Printf("Unable to parse offer file: %v\n", err)
-		os.Exit(1)
+		os.Remove(partial)
+		return &ParseError{File: dl.Filename, Err: err}
 	}
+	if err := os.Rename(partial, filename); err != nil {
+		return err
+	}
+	manifest.update(dl.Filename, ManifestEntry{URL: "file://" + source, PublicationDate: dl.PublicationDate,
+		LastModified: lastModified, Fetched: time.Now(), Size: size, SHA256: sum})
+	return nil
+}
 
-	// TODO paralellize
-	err = fetchOfferFile(offerIndex.Offers["AmazonEC2"].CurrentVersionURL, "AmazonEC2.json")
+func (c *Client) processOfferJSON(ctx context.Context, manifest *CacheManifest, regions []string) error {
+	offerIndex, err := c.readOfferIndex()
 	if err != nil {
-		log.Printf("Failed to download EC2 offer: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
-	err = fetchOfferFile(offerIndex.Offers["AmazonRDS"].CurrentVersionURL, "AmazonRDS.json")
+	failed := make(DownloadError)
+	downloads := make([]offerDownload, 0, len(offerFiles))
+	for _, code := range offerFiles {
+		offer, ok := offerIndex.Offers[code]
+		if !ok {
+			failed[code] = &MissingError{What: code + " offer in the offer index"}
+			continue
+		}
+		if len(regions) > 0 {
+			regional, err := c.regionalDownloads(ctx, offer, regions, manifest)
+			if err != nil {
+				failed[code] = err
+			}
+			downloads = append(downloads, regional...)
+			continue
+		}
+		downloads = append(downloads, offerDownload{Label: code, URL: formatURL(offer.CurrentVersionURL, c.offerFormat()),
+			Filename: code + "." + c.offerFormat(), PublicationDate: offerIndex.PublicationDate, OfferCode: code})
+	}
+	spDownloads, err := c.savingsPlanDownloads(ctx, offerIndex.Offers[savingsPlanOffer], regions, manifest)
 	if err != nil {
-		log.Printf("Failed to download RDS offer: %v\n", err)
-		os.Exit(1)
+		failed[savingsPlanOffer] = err
 	}
+	downloads = append(downloads, spDownloads...)
+
+	for label, err := range c.downloadAll(ctx, downloads, manifest, downloadWorkers) {
+		failed[label] = err
+	}
+	if err := manifest.save(); err != nil {
+		c.logf("Unable to save cache manifest: %v\n", err)
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
 }
 
 // FetchJSON downloads all the AWS Pricing JSON files that
-// the tool is aware of how to utilize.
-func FetchJSON() {
-	updateOfferJSON()
-	processOfferJSON()
+// the tool is aware of how to utilize. Only offers that changed since
+// the last fetch are downloaded. Every offer is attempted; the
+// returned error lists any that failed.
+func (c *Client) FetchJSON(ctx context.Context) error {
+	return c.FetchRegionsJSON(ctx, nil)
+}
+
+// FetchRegionsJSON is FetchJSON limited to the given region codes, like
+// us-east-1. It downloads the much smaller per-region offer files.
+func (c *Client) FetchRegionsJSON(ctx context.Context, regions []string) error {
+	for _, region := range regions {
+		if _, ok := codeToRegion[region]; !ok {
+			return fmt.Errorf("Invalid Region %s", region)
+		}
+	}
+	if err := c.makeCacheDir(); err != nil {
+		return err
+	}
+	manifest := c.loadManifest()
+	if err := c.updateOfferJSON(ctx, manifest); err != nil {
+		return err
+	}
+	return c.processOfferJSON(ctx, manifest, regions)
 }
@@ -0,0 +1,269 @@
+package awsprice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClient returns a client with a fresh cache directory that fetches
+// from base, and limits the fetched offers to those in testdata. The
+// returned function removes the cache and restores the offers.
+func testClient(t *testing.T, base string) (*Client, func()) {
+	dir, err := ioutil.TempDir("", "awsprice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{CacheDir: dir}
+	if err := client.SetEndpoint(base); err != nil {
+		t.Fatal(err)
+	}
+	oldOfferFiles := offerFiles
+	offerFiles = []string{"AmazonEC2", "AmazonRDS"}
+	return client, func() {
+		offerFiles = oldOfferFiles
+		os.RemoveAll(dir)
+	}
+}
+
+// fixtureDir returns the testdata directory as a file:// endpoint
+func fixtureDir(t *testing.T) string {
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + dir
+}
+
+func checkFetchedEC2(t *testing.T, client *Client) {
+	priceDB := NewPriceDB()
+	if err := ec2Extractor.Extract(context.Background(), client, priceDB); err != nil {
+		t.Fatal(err)
+	}
+	offer, err := priceDB.Get(Query{Name: "m4.xlarge", Attr: map[string]string{"region": "us-west-2"}})
+	if err != nil {
+		t.Fatalf("Error getting fetched EC2 price: %v", err)
+	}
+	if offer.HourlyPrice() != 0.2 {
+		t.Errorf("Expected 0.2, got %v", offer.HourlyPrice())
+	}
+	if ec2 := offer.(EC2Offer); ec2.ClockGHz != 2.4 || ec2.MemoryGB != 16 || ec2.Arch != "x86_64" {
+		t.Errorf("Expected the specs to be parsed, got %+v", ec2)
+	}
+}
+
+func TestFetchJSONFromServer(t *testing.T) {
+	var mu sync.Mutex
+	downloads := 0
+	files := http.FileServer(http.Dir("testdata"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			mu.Lock()
+			downloads++
+			mu.Unlock()
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	client, cleanup := testClient(t, server.URL)
+	defer cleanup()
+
+	if err := client.FetchJSON(context.Background()); err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+	// index, EC2, RDS, savings plan index and one region
+	if downloads != 5 {
+		t.Errorf("Expected 5 downloads, got %d", downloads)
+	}
+	for _, name := range []string{"offer.json", "AmazonEC2.json", "AmazonRDS.json", "AWSComputeSavingsPlan-us-west-2.json"} {
+		if _, err := os.Stat(client.path(name)); err != nil {
+			t.Errorf("Expected %s to be cached: %v", name, err)
+		}
+	}
+	checkFetchedEC2(t, client)
+
+	// nothing has changed, so a second fetch downloads nothing
+	downloads = 0
+	if err := client.FetchJSON(context.Background()); err != nil {
+		t.Fatalf("Error re-fetching: %v", err)
+	}
+	if downloads != 0 {
+		t.Errorf("Expected no downloads on re-fetch, got %d", downloads)
+	}
+}
+
+func TestFetchJSONFromDirectory(t *testing.T) {
+	client, cleanup := testClient(t, fixtureDir(t))
+	defer cleanup()
+
+	if err := client.FetchJSON(context.Background()); err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+	checkFetchedEC2(t, client)
+}
+
+func TestFetchJSONReportsFailures(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+	client, cleanup := testClient(t, server.URL)
+	defer cleanup()
+	offerFiles = append(offerFiles, "AmazonEFS")
+
+	err := client.FetchJSON(context.Background())
+	failed, ok := err.(DownloadError)
+	if !ok {
+		t.Fatalf("Expected a DownloadError, got %v", err)
+	}
+	if _, ok := failed["AmazonEFS"]; !ok || len(failed) != 1 {
+		t.Errorf("Expected only AmazonEFS to fail, got %v", failed)
+	}
+	var missing *MissingError
+	if !errors.As(failed["AmazonEFS"], &missing) {
+		t.Errorf("Expected a MissingError, got %v", failed["AmazonEFS"])
+	}
+}
+
+func TestFetchJSONCancelled(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+	client, cleanup := testClient(t, server.URL)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := client.FetchJSON(ctx)
+	var network *NetworkError
+	if !errors.As(err, &network) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled NetworkError, got %v", err)
+	}
+}
+
+func TestFetchRegionsJSON(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+	client, cleanup := testClient(t, server.URL)
+	defer cleanup()
+
+	if err := client.FetchRegionsJSON(context.Background(), []string{"us-west-2"}); err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+	for name, expected := range map[string]bool{
+		"AmazonEC2.json":           false,
+		"AmazonEC2-us-west-2.json": true,
+		"AmazonEC2-eu-west-1.json": false,
+		"AmazonRDS-us-west-2.json": true,
+	} {
+		if _, err := os.Stat(client.path(name)); (err == nil) != expected {
+			t.Errorf("Expected %s cached to be %v", name, expected)
+		}
+	}
+	checkFetchedEC2(t, client)
+
+	if err := client.FetchRegionsJSON(context.Background(), []string{"xx-nowhere-1"}); err == nil {
+		t.Error("Expected an error for an unknown region")
+	}
+}
+
+// fetchFixtureOffers fetches the testdata offers in the given format to a
+// fresh cache and extracts EC2 and RDS from it
+func fetchFixtureOffers(t *testing.T, format string) *PriceDB {
+	client, cleanup := testClient(t, fixtureDir(t))
+	defer cleanup()
+	if err := client.SetFormat(format); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.FetchJSON(context.Background()); err != nil {
+		t.Fatalf("Error fetching %s offers: %v", format, err)
+	}
+	for _, code := range []string{"AmazonEC2", "AmazonRDS"} {
+		if _, err := os.Stat(client.path(code + "." + format)); err != nil {
+			t.Errorf("Expected %s.%s to be fetched: %v", code, format, err)
+		}
+	}
+	priceDB := NewPriceDB()
+	for _, ex := range []Extractor{ec2Extractor, rdsExtractor} {
+		if err := ex.Extract(context.Background(), client, priceDB); err != nil {
+			t.Fatalf("Error extracting %s offers: %v", format, err)
+		}
+	}
+	return priceDB
+}
+
+func TestFetchCSVMatchesJSON(t *testing.T) {
+	fromJSON := fetchFixtureOffers(t, FormatJSON)
+	fromCSV := fetchFixtureOffers(t, FormatCSV)
+	if len(fromJSON.EC2) != 2 || len(fromJSON.RDS) != 1 {
+		t.Fatalf("Expected 2 EC2 and 1 RDS offers, got %d and %d", len(fromJSON.EC2), len(fromJSON.RDS))
+	}
+	if !reflect.DeepEqual(fromJSON, fromCSV) {
+		t.Errorf("CSV offers differ from JSON:\n%+v\n%+v", fromCSV, fromJSON)
+	}
+}
+
+func TestFetchResumesPartialDownload(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/offers/v1.0/aws/AmazonEC2/current/index.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	half := len(content) / 2
+	var mu sync.Mutex
+	var ranges []string
+	interrupt := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Method != "GET" {
+			return
+		}
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		cut := interrupt
+		interrupt = false
+		mu.Unlock()
+		if cut {
+			// send half the file, then drop the connection
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:half])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "AmazonEC2.json", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	client, cleanup := testClient(t, server.URL)
+	defer cleanup()
+	dl := offerDownload{URL: "/offers/v1.0/aws/AmazonEC2/current/index.json", Filename: "AmazonEC2.json", OfferCode: "AmazonEC2"}
+
+	if err := client.fetchOfferFile(context.Background(), dl, client.loadManifest(), nil); err == nil {
+		t.Fatal("Expected the interrupted download to fail")
+	}
+	// a fresh run only has what's on disk to go on
+	manifest := client.loadManifest()
+	if entry, _ := manifest.entry(dl.Filename); entry.PartETag != `"v1"` {
+		t.Fatalf("Expected the partial's ETag to be saved, got %q", entry.PartETag)
+	}
+	if err := client.fetchOfferFile(context.Background(), dl, manifest, nil); err != nil {
+		t.Fatalf("Error resuming: %v", err)
+	}
+	if want := []string{"", fmt.Sprintf("bytes=%d-", half)}; !reflect.DeepEqual(ranges, want) {
+		t.Errorf("Expected requests with ranges %q, got %q", want, ranges)
+	}
+	got, err := ioutil.ReadFile(client.path(dl.Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Expected the resumed file to match the original, got %d of %d bytes", len(got), len(content))
+	}
+}
@@ -0,0 +1,182 @@
+package awsprice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// asOfLayout is the date format accepted for --as-of
+const asOfLayout = "2006-01-02"
+
+// OfferVersionIndex lists every published version of an offer, as
+// linked from JSONOffer.VersionIndexURL
+type OfferVersionIndex struct {
+	FormatVersion   string                  `json:"formatVersion"`
+	Disclaimer      string                  `json:"disclaimer"`
+	PublicationDate string                  `json:"publicationDate"`
+	OfferCode       string                  `json:"offerCode"`
+	CurrentVersion  string                  `json:"currentVersion"`
+	Versions        map[string]OfferVersion `json:"versions"`
+}
+
+// OfferVersion identifies a single historical Offer File
+type OfferVersion struct {
+	VersionEffectiveBeginDate string `json:"versionEffectiveBeginDate"`
+	VersionEffectiveEndDate   string `json:"versionEffectiveEndDate"`
+	OfferVersionURL           string `json:"offerVersionUrl"`
+}
+
+// ParseAsOf parses an --as-of date such as 2023-01-01
+func ParseAsOf(given string) (time.Time, error) {
+	date, err := time.Parse(asOfLayout, given)
+	if err != nil {
+		return date, fmt.Errorf("Invalid date %s, expected YYYY-MM-DD", given)
+	}
+	return date, nil
+}
+
+// effectiveVersion returns the version of an offer that was in effect
+// on date. If versions overlap, the one that began most recently wins,
+// then the one with the greatest id, so the choice doesn't depend on
+// map order.
+func effectiveVersion(index OfferVersionIndex, date time.Time) (string, OfferVersion, error) {
+	var bestID string
+	var best OfferVersion
+	var bestBegin time.Time
+	for id, version := range index.Versions {
+		begin, err := time.Parse(time.RFC3339, version.VersionEffectiveBeginDate)
+		if err != nil || date.Before(begin) {
+			continue
+		}
+		if version.VersionEffectiveEndDate != "" {
+			end, err := time.Parse(time.RFC3339, version.VersionEffectiveEndDate)
+			if err != nil || !date.Before(end) {
+				continue
+			}
+		}
+		if bestID == "" || begin.After(bestBegin) || (begin.Equal(bestBegin) && id > bestID) {
+			bestID, best, bestBegin = id, version, begin
+		}
+	}
+	if bestID == "" {
+		return "", OfferVersion{}, &MissingError{What: fmt.Sprintf("%s version effective on %s", index.OfferCode, date.Format(asOfLayout))}
+	}
+	return bestID, best, nil
+}
+
+// snapshot returns a client for the cache directory holding the offers
+// and summary DB as of a date
+func (c *Client) snapshot(date time.Time) *Client {
+	snapshot := *c
+	snapshot.CacheDir = filepath.Join(c.CacheDir, "as-of", date.Format(asOfLayout))
+	return &snapshot
+}
+
+// versionDownload fetches an offer's version index and returns the
+// download of the version effective on date
+func (c *Client) versionDownload(ctx context.Context, offer JSONOffer, date time.Time, manifest *CacheManifest) (offerDownload, error) {
+	indexName := offer.OfferCode + "-versions.json"
+	err := c.fetchOfferFile(ctx, offerDownload{Label: indexName, URL: offer.VersionIndexURL, Filename: indexName}, manifest, nil)
+	if err != nil {
+		return offerDownload{}, err
+	}
+	file, err := ioutil.ReadFile(c.path(indexName))
+	if err != nil {
+		return offerDownload{}, err
+	}
+	var versionIndex OfferVersionIndex
+	err = json.Unmarshal(file, &versionIndex)
+	if err != nil {
+		return offerDownload{}, &ParseError{File: indexName, Err: err}
+	}
+	id, version, err := effectiveVersion(versionIndex, date)
+	if err != nil {
+		return offerDownload{}, err
+	}
+	// versions never change once published, so the id serves as the
+	// publication date
+	return offerDownload{Label: offer.OfferCode, URL: version.OfferVersionURL,
+		Filename: offer.OfferCode + ".json", PublicationDate: id, OfferCode: offer.OfferCode}, nil
+}
+
+// FetchJSONAsOf downloads the version of each offer that was in effect
+// on date into a separate, dated snapshot. Savings plans aren't
+// included, as they have no per-date versions of their own.
+func (c *Client) FetchJSONAsOf(ctx context.Context, date time.Time) error {
+	if err := c.makeCacheDir(); err != nil {
+		return err
+	}
+	manifest := c.loadManifest()
+	if err := c.updateOfferJSON(ctx, manifest); err != nil {
+		return err
+	}
+	if err := manifest.save(); err != nil {
+		c.logf("Unable to save cache manifest: %v\n", err)
+	}
+	offerIndex, err := c.readOfferIndex()
+	if err != nil {
+		return err
+	}
+
+	snapshot := c.snapshot(date)
+	if err := snapshot.makeCacheDir(); err != nil {
+		return err
+	}
+	snapshotManifest := snapshot.loadManifest()
+	failed := make(DownloadError)
+	downloads := make([]offerDownload, 0, len(offerFiles))
+	for _, code := range offerFiles {
+		offer, ok := offerIndex.Offers[code]
+		if !ok {
+			failed[code] = &MissingError{What: code + " offer in the offer index"}
+			continue
+		}
+		dl, err := snapshot.versionDownload(ctx, offer, date, snapshotManifest)
+		if err != nil {
+			failed[code] = err
+			continue
+		}
+		downloads = append(downloads, dl)
+	}
+	for label, err := range snapshot.downloadAll(ctx, downloads, snapshotManifest, downloadWorkers) {
+		failed[label] = err
+	}
+	if err := snapshotManifest.save(); err != nil {
+		c.logf("Unable to save cache manifest: %v\n", err)
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// ProcessJSONAsOf builds the summary DB for the snapshot fetched by
+// FetchJSONAsOf
+func (c *Client) ProcessJSONAsOf(ctx context.Context, date time.Time) error {
+	return c.snapshot(date).ProcessJSON(ctx)
+}
+
+// LoadPriceDBAsOf loads the summary DB of a dated snapshot
+func (c *Client) LoadPriceDBAsOf(date time.Time) (*PriceDB, error) {
+	return c.snapshot(date).LoadPriceDB()
+}
+
+// LoadSnapshot loads a DB by name: "current" for the latest processed
+// prices, a YYYY-MM-DD date for a snapshot from FetchJSONAsOf, or
+// otherwise the path to a saved gob or .bolt DB file
+func (c *Client) LoadSnapshot(name string) (*PriceDB, error) {
+	if name == "current" {
+		return c.LoadPriceDB()
+	}
+	if date, err := ParseAsOf(name); err == nil {
+		return c.LoadPriceDBAsOf(date)
+	}
+	if filepath.Ext(name) == ".bolt" {
+		return BoltBackend{}.Load(name)
+	}
+	return LoadPriceDBFile(name)
+}
@@ -0,0 +1,71 @@
+package awsprice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEffectiveVersion(t *testing.T) {
+	index := OfferVersionIndex{OfferCode: "AmazonEC2", Versions: map[string]OfferVersion{
+		"new": {VersionEffectiveBeginDate: "2023-01-01T00:00:00Z"},
+		"old": {VersionEffectiveBeginDate: "2022-01-01T00:00:00Z", VersionEffectiveEndDate: "2023-01-01T00:00:00Z"},
+	}}
+	cases := map[string]string{"2022-06-01": "old", "2023-01-01": "new", "2024-01-01": "new"}
+	for given, expected := range cases {
+		date, _ := ParseAsOf(given)
+		id, _, err := effectiveVersion(index, date)
+		if err != nil || id != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", given, expected, id, err)
+		}
+	}
+	date, _ := ParseAsOf("2021-01-01")
+	if _, _, err := effectiveVersion(index, date); err == nil {
+		t.Error("Expected an error before the first version")
+	}
+
+	// overlapping versions, with no end dates and a shared begin date
+	overlapping := OfferVersionIndex{OfferCode: "AmazonEC2", Versions: map[string]OfferVersion{
+		"20220101000000": {VersionEffectiveBeginDate: "2022-01-01T00:00:00Z"},
+		"20220301000000": {VersionEffectiveBeginDate: "2022-03-01T00:00:00Z"},
+		"20220302000000": {VersionEffectiveBeginDate: "2022-03-01T00:00:00Z"},
+		"20230101000000": {VersionEffectiveBeginDate: "2023-01-01T00:00:00Z"},
+	}}
+	cases = map[string]string{"2022-02-01": "20220101000000", "2022-06-01": "20220302000000", "2023-06-01": "20230101000000"}
+	for given, expected := range cases {
+		date, _ := ParseAsOf(given)
+		// map order varies from run to run, so try a few times
+		for i := 0; i < 20; i++ {
+			if id, _, err := effectiveVersion(overlapping, date); err != nil || id != expected {
+				t.Fatalf("%s: expected %s, got %s (%v)", given, expected, id, err)
+			}
+		}
+	}
+}
+
+func TestPriceDBAsOf(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+	client, cleanup := testClient(t, server.URL)
+	defer cleanup()
+
+	date, _ := ParseAsOf("2022-06-01")
+	if err := client.FetchJSONAsOf(context.Background(), date); err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+	if err := client.ProcessJSONAsOf(context.Background(), date); err != nil {
+		t.Fatalf("Error processing: %v", err)
+	}
+	priceDB, err := client.LoadPriceDBAsOf(date)
+	if err != nil {
+		t.Fatalf("Error loading snapshot: %v", err)
+	}
+	offer, err := priceDB.Get(Query{Name: "m4.xlarge", Attr: map[string]string{"region": "us-west-2"}})
+	if err != nil {
+		t.Fatalf("Error getting price: %v", err)
+	}
+	if offer.HourlyPrice() != 0.25 {
+		t.Errorf("Expected the 2022 price of 0.25, got %v", offer.HourlyPrice())
+	}
+}
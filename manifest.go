@@ -0,0 +1,195 @@
+package awsprice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const manifestFile = "manifest.json"
+
+// CacheManifest records the version of each file in the cache, so a
+// fetch can skip offers that haven't changed and processing can reuse
+// the extracted data of files it has already seen.
+type CacheManifest struct {
+	mu    sync.Mutex
+	dir   string
+	Files map[string]ManifestEntry `json:"files"`
+}
+
+// ManifestEntry describes one cached file
+type ManifestEntry struct {
+	URL             string    `json:"url"`
+	PublicationDate string    `json:"publicationDate"`
+	ETag            string    `json:"etag"`
+	LastModified    string    `json:"lastModified"`
+	Fetched         time.Time `json:"fetched"`
+	// Size and SHA256 are those of the file as verified when fetched
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	// PartETag is the version a leftover .part file belongs to
+	PartETag string `json:"partEtag,omitempty"`
+	// Processed is set once the file has been extracted into the DB
+	Processed bool `json:"processed"`
+}
+
+// loadManifest reads the client's cache manifest. A missing or
+// unreadable manifest is treated as empty, meaning everything is
+// re-checked.
+func (c *Client) loadManifest() *CacheManifest {
+	manifest := &CacheManifest{dir: c.CacheDir, Files: make(map[string]ManifestEntry)}
+	file, err := ioutil.ReadFile(c.path(manifestFile))
+	if err != nil {
+		return manifest
+	}
+	if err := json.Unmarshal(file, manifest); err != nil || manifest.Files == nil {
+		manifest.Files = make(map[string]ManifestEntry)
+	}
+	return manifest
+}
+
+func (cm *CacheManifest) save() error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	data, err := json.MarshalIndent(cm, "", "  ")
+	if err != nil {
+		return err
+	}
+	return replaceFile(filepath.Join(cm.dir, manifestFile), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// entry returns the manifest entry for a cached file, if the file is
+// both recorded and present on disk at its recorded size
+func (cm *CacheManifest) entry(name string) (ManifestEntry, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	entry, ok := cm.Files[name]
+	if !ok {
+		return entry, false
+	}
+	info, err := os.Stat(filepath.Join(cm.dir, name))
+	if err != nil || (entry.Size != 0 && info.Size() != entry.Size) {
+		return entry, false
+	}
+	return entry, true
+}
+
+// verify re-hashes a cached file, checking it's the file that was
+// fetched. Entries recorded without a hash, and files no longer in the
+// cache, aren't checked.
+func (cm *CacheManifest) verify(name string) error {
+	cm.mu.Lock()
+	entry, ok := cm.Files[name]
+	cm.mu.Unlock()
+	if !ok || entry.SHA256 == "" {
+		return nil
+	}
+	sum, err := fileSHA256(filepath.Join(cm.dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if sum != entry.SHA256 {
+		return fmt.Errorf("SHA-256 %s doesn't match the %s recorded when it was fetched", sum, entry.SHA256)
+	}
+	return nil
+}
+
+// forget drops a file's entry, so the next fetch downloads it again
+func (cm *CacheManifest) forget(name string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	delete(cm.Files, name)
+}
+
+func (cm *CacheManifest) update(name string, entry ManifestEntry) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.Files[name] = entry
+}
+
+// offerFileName reports whether a cache file holds offers for a code:
+// <code>.json or .csv, or a per-region <code>-<region> file, as read by
+// offerPaths. Other <code>- files, like the region and version
+// indexes, don't.
+func offerFileName(code, name string) bool {
+	ext := filepath.Ext(name)
+	if ext != "."+FormatJSON && ext != "."+FormatCSV {
+		return false
+	}
+	base := strings.TrimSuffix(name, ext)
+	if base == code {
+		return true
+	}
+	if !strings.HasPrefix(base, code+"-") {
+		return false
+	}
+	_, ok := codeToRegion[strings.TrimPrefix(base, code+"-")]
+	return ok
+}
+
+// offerFileNames returns the recorded cache files belonging to an
+// offer code, see offerFileName
+func (cm *CacheManifest) offerFileNames(code string) []string {
+	names := make([]string, 0, 1)
+	for name := range cm.Files {
+		if offerFileName(code, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// processed reports whether every cached file of an offer has already
+// been extracted into the current DB
+func (cm *CacheManifest) processed(code string) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	names := cm.offerFileNames(code)
+	if len(names) == 0 {
+		return false
+	}
+	for _, name := range names {
+		if !cm.Files[name].Processed {
+			return false
+		}
+	}
+	return true
+}
+
+// publicationDate returns the newest publication date among the
+// cached files of an offer
+func (cm *CacheManifest) publicationDate(code string) string {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	latest := ""
+	for _, name := range cm.offerFileNames(code) {
+		if date := cm.Files[name].PublicationDate; date > latest {
+			latest = date
+		}
+	}
+	return latest
+}
+
+// markProcessed records that all the cached files of an offer are
+// reflected in the DB
+func (cm *CacheManifest) markProcessed(code string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	for _, name := range cm.offerFileNames(code) {
+		entry := cm.Files[name]
+		entry.Processed = true
+		cm.Files[name] = entry
+	}
+}
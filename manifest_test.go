@@ -0,0 +1,80 @@
+package awsprice
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+func TestOfferFileName(t *testing.T) {
+	cases := []struct {
+		code, name string
+		expected   bool
+	}{
+		{"AmazonEC2", "AmazonEC2.json", true},
+		{"AmazonEC2", "AmazonEC2.csv", true},
+		{"AmazonEC2", "AmazonEC2-us-west-2.json", true},
+		{"AmazonEC2", "AmazonEC2-eu-west-1.csv", true},
+		{"AmazonEC2", "AmazonEC2-regions.json", false},
+		{"AmazonEC2", "AmazonEC2-versions.json", false},
+		{"AmazonEC2", "AmazonEC2-us-west-2.json.tmp", false},
+		{"AmazonEC2", "AmazonEC2Extra.json", false},
+		{savingsPlanOffer, "AWSComputeSavingsPlan-us-west-2.json", true},
+		{savingsPlanOffer, "AWSComputeSavingsPlan-index.json", false},
+	}
+	for _, c := range cases {
+		if got := offerFileName(c.code, c.name); got != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestOfferCachedIgnoresIndexes(t *testing.T) {
+	client, cleanup := testClient(t, DefaultEndpoint)
+	defer cleanup()
+	if err := client.makeCacheDir(); err != nil {
+		t.Fatal(err)
+	}
+	manifest := client.loadManifest()
+	for _, name := range []string{"AmazonEC2-regions.json", "AmazonEC2-versions.json"} {
+		if err := ioutil.WriteFile(client.path(name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		manifest.update(name, ManifestEntry{Processed: true})
+	}
+	// the indexes of a fetch whose offer download failed
+	if client.offerCached("AmazonEC2") || manifest.processed("AmazonEC2") {
+		t.Error("Expected the region and version indexes not to count as a cached offer")
+	}
+	if err := ioutil.WriteFile(client.path("AmazonEC2-us-west-2.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest.update("AmazonEC2-us-west-2.json", ManifestEntry{})
+	if !client.offerCached("AmazonEC2") || manifest.processed("AmazonEC2") {
+		t.Error("Expected the regional offer to be cached and unprocessed")
+	}
+}
+
+func TestProcessMarksOnlyExtractedOffers(t *testing.T) {
+	client, cleanup := testClient(t, fixtureDir(t))
+	defer cleanup()
+	if err := client.FetchJSON(context.Background()); err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+	// recorded, but gone from the cache, so there's nothing to extract
+	manifest := client.loadManifest()
+	manifest.update("AmazonEFS.json", ManifestEntry{})
+	if err := manifest.save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ProcessJSON(context.Background()); err != nil {
+		t.Fatalf("Error processing: %v", err)
+	}
+	manifest = client.loadManifest()
+	if !manifest.processed("AmazonEC2") {
+		t.Error("Expected the extracted EC2 offer to be marked processed")
+	}
+	if manifest.Files["AmazonEFS.json"].Processed {
+		t.Error("Expected the missing EFS offer not to be marked processed")
+	}
+}
@@ -1,62 +1,105 @@
 package awsprice
 
-import "fmt"
-
-// EC2OfferIndex is at the root of the EC2 Offer JSON document
-type EC2OfferIndex struct {
-	FormatVersion   string                `json:"formatVersion"`
-	Disclaimer      string                `json:"disclaimer"`
-	PublicationDate string                `json:"publicationDate"`
-	Products        map[string]EC2Product `json:"products"`
-	Terms           EC2Terms              `json:"terms"`
-}
-
-// EC2Product identifies a single product 'leaf' in the JSON document
-type EC2Product struct {
-	SKU           string  `json:"sku"`
-	ProductFamily string  `json:"productFamily"`
-	Attr          EC2Attr `json:"attributes"`
-}
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // EC2Attr identifies a selected list of useful attributes
 type EC2Attr struct {
-	ServiceCode       string `json:"servicecode"`
-	Location          string `json:"location"`
-	LocationType      string `json:"locationType"`
-	InstanceType      string `json:"instanceType"`
-	CurrentGeneration string `json:"currentGeneration"`
-	InstanceFamily    string `json:"instanceFamily"`
-	VCPU              string `json:"vcpu"`
-	Memory            string `json:"memory"`
-	OperatingSystem   string `json:"operatingSystem"`
-	Tenancy           string `json:"tenancy"`
+	ServiceCode            string `json:"servicecode"`
+	Location               string `json:"location"`
+	LocationType           string `json:"locationType"`
+	InstanceType           string `json:"instanceType"`
+	CurrentGeneration      string `json:"currentGeneration"`
+	InstanceFamily         string `json:"instanceFamily"`
+	VCPU                   string `json:"vcpu"`
+	Memory                 string `json:"memory"`
+	OperatingSystem        string `json:"operatingSystem"`
+	Tenancy                string `json:"tenancy"`
+	ProcessorArchitecture  string `json:"processorArchitecture"`
+	ClockSpeed             string `json:"clockSpeed"`
+	PhysicalProcessor      string `json:"physicalProcessor"`
+	NetworkPerformance     string `json:"networkPerformance"`
+	Storage                string `json:"storage"`
+	DedicatedEBSThroughput string `json:"dedicatedEbsThroughput"`
+	GPU                    string `json:"gpu"`
+	GPUMemory              string `json:"gpuMemory"`
 }
 
-// EC2Terms tracks the various terms. For now only OnDemand (not prepaid/spot/etc) is used.
-type EC2Terms struct {
-	OnDemand map[string]map[string]EC2TermItem
+// EC2Offer The product/price details for a given EC2 Offering
+type EC2Offer struct {
+	Product EC2Attr
+	Price   float64
+	// The rest are parsed from Product, for searching by spec. See
+	// parseSpecs.
+	VCPUs       float64
+	MemoryGB    float64
+	ClockGHz    float64
+	NetworkGbps float64
+	EBSMbps     float64
+	GPUs        float64
+	GPUMemoryGB float64
+	// Arch is arm64 or x86_64
+	Arch string
+	// Burstable is set for the T types, which earn and spend CPU credits
+	Burstable bool
 }
 
-// EC2TermItem is a given pricing term
-type EC2TermItem struct {
-	OfferTermCode   string                        `json:"offerTermCode"`
-	SKU             string                        `json:"sku"`
-	PriceDimensions map[string]EC2PriceDimensions `json:"priceDimensions"`
+// specNumber reads the number from a spec like "Up to 3.5 GHz" or
+// "1,952 GiB", along with its unit. Descriptions like "High" or "NA"
+// don't parse.
+func specNumber(spec string) (Quantity, bool) {
+	spec = strings.TrimPrefix(strings.TrimSpace(spec), "Up to ")
+	q, err := ParseQuantity(strings.Replace(spec, ",", "", -1))
+	return q, err == nil
 }
 
-// EC2PriceDimensions stores various combinations of billing duration
-// and currency
-type EC2PriceDimensions struct {
-	RateCode     string            `json:"rateCode"`
-	Description  string            `json:"description"`
-	Unit         string            `json:"unit"`
-	PricePerUnit map[string]string `json:"pricePerUnit"`
+// parseSpecs fills in the numeric specs, architecture and burstability
+// from the product attributes. Specs that don't parse are left at zero.
+func (eo *EC2Offer) parseSpecs() {
+	p := eo.Product
+	if vcpus, err := strconv.ParseFloat(strings.TrimSpace(p.VCPU), 64); err == nil {
+		eo.VCPUs = vcpus
+	}
+	if memory, ok := specNumber(p.Memory); ok {
+		eo.MemoryGB, _ = memory.GB()
+	}
+	if clock, ok := specNumber(p.ClockSpeed); ok {
+		eo.ClockGHz = clock.Value
+	}
+	if network, ok := specNumber(p.NetworkPerformance); ok {
+		eo.NetworkGbps, _ = network.Gbps()
+	}
+	if ebs, ok := specNumber(p.DedicatedEBSThroughput); ok {
+		eo.EBSMbps = ebs.Value
+	}
+	if gpus, ok := specNumber(p.GPU); ok {
+		eo.GPUs = gpus.Value
+	}
+	if gpuMemory, ok := specNumber(p.GPUMemory); ok {
+		eo.GPUMemoryGB, _ = gpuMemory.GB()
+	}
+	eo.Arch = productArch(p)
+	eo.Burstable = len(p.InstanceType) > 1 && p.InstanceType[0] == 't' &&
+		p.InstanceType[1] >= '0' && p.InstanceType[1] <= '9'
 }
 
-// EC2Offer The product/price details for a given EC2 Offering
-type EC2Offer struct {
-	Product EC2Attr
-	Price   float64
+// productArch returns the architecture an instance type's
+// processorArchitecture names, like "64-bit Arm". Often it only says
+// "64-bit", so the processor name is the fallback: Graviton and Apple
+// processors are arm64, anything else is x86_64.
+func productArch(p EC2Attr) string {
+	for _, word := range strings.Fields(strings.ToLower(p.ProcessorArchitecture)) {
+		if arch, ok := archNames[word]; ok {
+			return arch
+		}
+	}
+	if strings.Contains(p.PhysicalProcessor, "Graviton") || strings.Contains(p.PhysicalProcessor, "Apple") {
+		return "arm64"
+	}
+	return "x86_64"
 }
 
 // Name returns the EC2 instance type
@@ -95,6 +138,47 @@ func NewEC2OfferParam(name string, attr map[string]string) (EC2OfferParam, error
 	return *offerParams, nil
 }
 
+// storeIn adds the offer to a PriceDB under its EC2OfferParam
+func (eo EC2Offer) storeIn(pd *PriceDB, key OfferKey) error {
+	param, err := NewEC2OfferParam(key.Name, key.Attr)
+	if err != nil {
+		return err
+	}
+	pd.register(key.Name, EC2)
+	pd.EC2[param] = eo
+	return nil
+}
+
+// getEC2 looks up an EC2 offer, at its spot or savings plan price if
+// the query asks for one
+func (pd *PriceDB) getEC2(q Query) (Offer, error) {
+	if q.Attr["market"] == "spot" {
+		attr, err := spotAttr(q.Attr)
+		if err != nil {
+			return nil, err
+		}
+		q.Attr = attr
+	}
+	param, err := NewEC2OfferParam(q.Name, q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	offer, ok := pd.EC2[param]
+	if !ok {
+		return nil, &MissingError{What: "matching EC2 records"}
+	}
+	switch q.Attr["market"] {
+	case "", "ondemand":
+		if _, ok := q.Attr["sp"]; ok {
+			return pd.ec2SavingsPlan(offer, param, q.Attr)
+		}
+		return offer, nil
+	case "spot":
+		return pd.spotOffer(offer, param, q.Attr)
+	}
+	return nil, fmt.Errorf("Unknown market %s", q.Attr["market"])
+}
+
 // String returns a simple string version of the pricing
 func (eo EC2Offer) String() string {
 	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo", eo.HourlyPrice(), eo.HourlyPrice()*HoursPerMonth)
@@ -110,3 +194,37 @@ func (eo EC2Offer) Columns() []string {
 func (eo EC2Offer) RowData() []string {
 	return []string{eo.Product.InstanceType, eo.Product.VCPU, eo.Product.Memory, fmt.Sprintf("$%0.3f", eo.Price), fmt.Sprintf("$%0.2f", eo.Price*HoursPerMonth)}
 }
+
+// Describe lists the instance's specs for the describe view
+func (eo EC2Offer) Describe() [][]string {
+	p := eo.Product
+	burstable := "No"
+	if eo.Burstable {
+		burstable = "Yes"
+	}
+	rows := [][]string{
+		{"Region", p.Location},
+		{"Family", p.InstanceFamily},
+		{"Current generation", p.CurrentGeneration},
+		{"vCPU", p.VCPU},
+		{"Memory", p.Memory},
+		{"Processor", p.PhysicalProcessor},
+		{"Architecture", eo.Arch},
+		{"Clock speed", p.ClockSpeed},
+		{"Network", p.NetworkPerformance},
+		{"Instance storage", p.Storage},
+		{"EBS throughput", p.DedicatedEBSThroughput},
+		{"GPUs", p.GPU},
+		{"GPU memory", p.GPUMemory},
+		{"Burstable", burstable},
+		{"Operating system", p.OperatingSystem},
+		{"Tenancy", p.Tenancy},
+	}
+	described := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		if row[1] != "" && row[1] != "NA" {
+			described = append(described, row)
+		}
+	}
+	return described
+}
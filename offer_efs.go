@@ -0,0 +1,188 @@
+package awsprice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EFSAttr identifies a selected list of useful attributes
+type EFSAttr struct {
+	ServiceCode     string `json:"servicecode"`
+	Location        string `json:"location"`
+	LocationType    string `json:"locationType"`
+	StorageClass    string `json:"storageClass"`
+	ThroughputClass string `json:"throughputClass"`
+	AccessType      string `json:"accessType"`
+	UsageType       string `json:"usagetype"`
+}
+
+// EFS throughput modes
+const (
+	EFSBursting    = "bursting"
+	EFSProvisioned = "provisioned"
+	EFSElastic     = "elastic"
+)
+
+// efsStorageClasses maps the short class names accepted as arguments
+// to the storageClass used in the offer file
+var efsStorageClasses = map[string]string{
+	"standard":   "General Purpose",
+	"ia":         "Infrequent Access",
+	"onezone":    "One Zone-General Purpose",
+	"onezone-ia": "One Zone-Infrequent Access",
+	"archive":    "Archive",
+}
+
+// EFSThroughputPrices are the per-region throughput charges that apply
+// on top of storage, depending on the throughput mode
+type EFSThroughputPrices struct {
+	Provisioned  float64 // per MB/s-month
+	ElasticRead  float64 // per GB read
+	ElasticWrite float64 // per GB written
+}
+
+// EFSOffer The product/price details for a given EFS storage class.
+// Price is per GB-month; Size, Mode and the usage fields are filled in
+// from the request when the offer is looked up.
+type EFSOffer struct {
+	Product    EFSAttr
+	Price      float64
+	Throughput EFSThroughputPrices
+	Size       float64
+	Mode       string
+	Provision  float64
+	ReadGB     float64
+	WriteGB    float64
+}
+
+// Name returns the EFS offer name
+func (eo EFSOffer) Name() string {
+	return "efs"
+}
+
+// MonthlyPrice returns the dollars per month for the configured usage
+func (eo EFSOffer) MonthlyPrice() float64 {
+	monthly := eo.Size * eo.Price
+	switch eo.Mode {
+	case EFSProvisioned:
+		monthly += eo.Provision * eo.Throughput.Provisioned
+	case EFSElastic:
+		monthly += eo.ReadGB*eo.Throughput.ElasticRead + eo.WriteGB*eo.Throughput.ElasticWrite
+	}
+	return monthly
+}
+
+// HourlyPrice returns the fractional dollars per hour
+func (eo EFSOffer) HourlyPrice() float64 {
+	return eo.MonthlyPrice() / HoursPerMonth
+}
+
+// Type always returns EFS
+func (eo EFSOffer) Type() OfferType {
+	return EFS
+}
+
+// EFSOfferParam stores the unique factors that determine an EFS Offer
+type EFSOfferParam struct {
+	Region       Region
+	StorageClass string
+}
+
+// NewEFSOfferParam constructs an EFS offer from a name & attributes
+func NewEFSOfferParam(name string, attr map[string]string) (EFSOfferParam, error) {
+	offerParams := &EFSOfferParam{}
+	if region, ok := attr["region"]; ok {
+		reg, err := NewRegion(region)
+		if err != nil {
+			return *offerParams, err
+		}
+		offerParams.Region = reg
+	} else {
+		offerParams.Region = defaultRegion
+	}
+	offerParams.StorageClass = efsStorageClasses["standard"]
+	if class, ok := attr["class"]; ok {
+		if full, ok := efsStorageClasses[strings.ToLower(class)]; ok {
+			offerParams.StorageClass = full
+		} else {
+			offerParams.StorageClass = class
+		}
+	}
+	return *offerParams, nil
+}
+
+// storeIn adds the offer to a PriceDB under its EFSOfferParam
+func (eo EFSOffer) storeIn(pd *PriceDB, key OfferKey) error {
+	param, err := NewEFSOfferParam(key.Name, key.Attr)
+	if err != nil {
+		return err
+	}
+	pd.register(key.Name, EFS)
+	pd.EFS[param] = eo
+	return nil
+}
+
+// getEFS looks up an EFS offer, sized by the query's attributes
+func (pd *PriceDB) getEFS(q Query) (Offer, error) {
+	param, err := NewEFSOfferParam(q.Name, q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	offer, ok := pd.EFS[param]
+	if !ok {
+		return nil, &MissingError{What: "matching EFS records"}
+	}
+	sized, err := offer.withUsage(q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	return sized, nil
+}
+
+// withUsage returns a copy of the offer sized by the given attributes:
+// size, mode, and throughput (provisioned) or read/write (elastic)
+func (eo EFSOffer) withUsage(attr map[string]string) (EFSOffer, error) {
+	var err error
+	if eo.Size, err = attrGB(attr, "size", 1); err != nil {
+		return eo, err
+	}
+	eo.Mode = EFSBursting
+	if mode, ok := attr["mode"]; ok {
+		eo.Mode = strings.ToLower(mode)
+	} else if _, ok := attr["throughput"]; ok {
+		eo.Mode = EFSProvisioned
+	}
+	switch eo.Mode {
+	case EFSBursting:
+	case EFSProvisioned:
+		if eo.Provision, err = attrMBps(attr, "throughput", 0); err != nil {
+			return eo, err
+		}
+	case EFSElastic:
+		if eo.ReadGB, err = attrGB(attr, "read", 0); err != nil {
+			return eo, err
+		}
+		if eo.WriteGB, err = attrGB(attr, "write", 0); err != nil {
+			return eo, err
+		}
+	default:
+		return eo, fmt.Errorf("Unknown EFS throughput mode %s", eo.Mode)
+	}
+	return eo, nil
+}
+
+// String returns a simple string version of the pricing
+func (eo EFSOffer) String() string {
+	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo (%0.0f GB %s, %s)", eo.HourlyPrice(), eo.MonthlyPrice(), eo.Size, eo.Product.StorageClass, eo.Mode)
+}
+
+// Columns returns a slice of the column names for this type
+func (eo EFSOffer) Columns() []string {
+	return []string{"class", "GB", "mode", "$/GB-mo", "$/hr", "$/mo"}
+}
+
+// RowData returns data for this item for tablular presentation
+// Should be used in concert with Columns
+func (eo EFSOffer) RowData() []string {
+	return []string{eo.Product.StorageClass, fmt.Sprintf("%0.0f", eo.Size), eo.Mode, fmt.Sprintf("$%0.4f", eo.Price), fmt.Sprintf("$%0.3f", eo.HourlyPrice()), fmt.Sprintf("$%0.2f", eo.MonthlyPrice())}
+}
@@ -0,0 +1,92 @@
+package awsprice
+
+import (
+	"context"
+	"testing"
+)
+
+// extractFixture runs an extractor over its offer file in
+// testdata/extract
+func extractFixture(t *testing.T, ex Extractor) *PriceDB {
+	client := &Client{CacheDir: "testdata/extract"}
+	db := NewPriceDB()
+	if err := ex.Extract(context.Background(), client, db); err != nil {
+		t.Fatalf("Error extracting %s: %v", ex.OfferCode(), err)
+	}
+	return db
+}
+
+// closeTo compares prices, allowing for floating point error
+func closeTo(got, expected float64) bool {
+	return got-expected < 1e-9 && expected-got < 1e-9
+}
+
+func TestExtractEFS(t *testing.T) {
+	db := extractFixture(t, efsExtractor)
+	// the GovCloud region and the IA access charge aren't offers
+	if len(db.EFS) != 5 {
+		t.Errorf("Expected 5 EFS offers, got %d", len(db.EFS))
+	}
+	cases := []struct {
+		attr       map[string]string
+		price      float64
+		throughput EFSThroughputPrices
+	}{
+		{map[string]string{}, 0.30, EFSThroughputPrices{Provisioned: 6, ElasticRead: 0.03, ElasticWrite: 0.06}},
+		{map[string]string{"class": "ia"}, 0.025, EFSThroughputPrices{Provisioned: 6, ElasticRead: 0.03, ElasticWrite: 0.06}},
+		{map[string]string{"class": "onezone"}, 0.16, EFSThroughputPrices{Provisioned: 6, ElasticRead: 0.03, ElasticWrite: 0.06}},
+		{map[string]string{"class": "OneZone-IA"}, 0.0133, EFSThroughputPrices{Provisioned: 6, ElasticRead: 0.03, ElasticWrite: 0.06}},
+		{map[string]string{"region": "eu-west-1"}, 0.33, EFSThroughputPrices{}},
+	}
+	for _, c := range cases {
+		offer, err := db.Get(Query{Name: "efs", Attr: c.attr})
+		if err != nil {
+			t.Errorf("%v: error getting EFS offer: %v", c.attr, err)
+			continue
+		}
+		efs := offer.(EFSOffer)
+		if efs.Price != c.price || efs.Throughput != c.throughput {
+			t.Errorf("%v: expected %v with %+v, got %v with %+v", c.attr, c.price, c.throughput, efs.Price, efs.Throughput)
+		}
+	}
+	if _, err := db.Get(Query{Name: "efs", Attr: map[string]string{"class": "archive"}}); err == nil {
+		t.Error("Expected no archive offer in the fixture")
+	}
+}
+
+func TestEFSMonthlyPrice(t *testing.T) {
+	offer := EFSOffer{Price: 0.30, Throughput: EFSThroughputPrices{Provisioned: 6, ElasticRead: 0.03, ElasticWrite: 0.06}}
+	cases := []struct {
+		attr    map[string]string
+		mode    string
+		monthly float64
+	}{
+		{map[string]string{}, EFSBursting, 0.30},
+		{map[string]string{"size": "100GB"}, EFSBursting, 30},
+		{map[string]string{"size": "1TB", "mode": "bursting"}, EFSBursting, 1024 * 0.30},
+		{map[string]string{"size": "100GB", "throughput": "10MBps"}, EFSProvisioned, 30 + 10*6},
+		{map[string]string{"size": "100GB", "mode": "provisioned"}, EFSProvisioned, 30},
+		{map[string]string{"size": "100GB", "mode": "Elastic", "read": "50GB", "write": "10GB"}, EFSElastic, 30 + 50*0.03 + 10*0.06},
+	}
+	for _, c := range cases {
+		sized, err := offer.withUsage(c.attr)
+		if err != nil {
+			t.Errorf("%v: error sizing offer: %v", c.attr, err)
+			continue
+		}
+		if sized.Mode != c.mode || !closeTo(sized.MonthlyPrice(), c.monthly) {
+			t.Errorf("%v: expected %s at $%v /mo, got %s at $%v", c.attr, c.mode, c.monthly, sized.Mode, sized.MonthlyPrice())
+		}
+		if !closeTo(sized.HourlyPrice(), c.monthly/HoursPerMonth) {
+			t.Errorf("%v: expected $%v /hr, got $%v", c.attr, c.monthly/HoursPerMonth, sized.HourlyPrice())
+		}
+		if len(sized.RowData()) != len(sized.Columns()) || sized.RowData()[2] != c.mode {
+			t.Errorf("%v: unexpected row %v for %v", c.attr, sized.RowData(), sized.Columns())
+		}
+	}
+	for _, attr := range []map[string]string{{"mode": "turbo"}, {"size": "100furlongs"}, {"throughput": "10MBps", "mode": "provisioned", "size": "x"}} {
+		if _, err := offer.withUsage(attr); err == nil {
+			t.Errorf("%v: expected an error", attr)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package awsprice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EKSOffer The per-cluster control plane price for EKS. ExtendedPrice
+// applies to clusters running a Kubernetes version in extended support.
+type EKSOffer struct {
+	Location      string
+	Price         float64
+	ExtendedPrice float64
+	Clusters      float64
+	Extended      bool
+}
+
+// Name returns the EKS offer name
+func (eo EKSOffer) Name() string {
+	return "eks"
+}
+
+// HourlyPrice returns the fractional dollars per hour for all clusters
+func (eo EKSOffer) HourlyPrice() float64 {
+	if eo.Extended {
+		return eo.Clusters * eo.ExtendedPrice
+	}
+	return eo.Clusters * eo.Price
+}
+
+// Type always returns EKS
+func (eo EKSOffer) Type() OfferType {
+	return EKS
+}
+
+// EKSOfferParam stores the unique factors that determine an EKS Offer
+type EKSOfferParam struct {
+	Region Region
+}
+
+// NewEKSOfferParam constructs an EKS offer from a name & attributes
+func NewEKSOfferParam(name string, attr map[string]string) (EKSOfferParam, error) {
+	offerParams := &EKSOfferParam{}
+	if region, ok := attr["region"]; ok {
+		reg, err := NewRegion(region)
+		if err != nil {
+			return *offerParams, err
+		}
+		offerParams.Region = reg
+	} else {
+		offerParams.Region = defaultRegion
+	}
+	return *offerParams, nil
+}
+
+// storeIn adds the offer to a PriceDB under its EKSOfferParam
+func (eo EKSOffer) storeIn(pd *PriceDB, key OfferKey) error {
+	param, err := NewEKSOfferParam(key.Name, key.Attr)
+	if err != nil {
+		return err
+	}
+	pd.register(key.Name, EKS)
+	pd.EKS[param] = eo
+	return nil
+}
+
+// getEKS looks up an EKS offer, sized by the query's attributes
+func (pd *PriceDB) getEKS(q Query) (Offer, error) {
+	param, err := NewEKSOfferParam(q.Name, q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	offer, ok := pd.EKS[param]
+	if !ok {
+		return nil, &MissingError{What: "matching EKS records"}
+	}
+	sized, err := offer.withUsage(q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	return sized, nil
+}
+
+// withUsage returns a copy of the offer for the clusters and support
+// (standard or extended) attributes
+func (eo EKSOffer) withUsage(attr map[string]string) (EKSOffer, error) {
+	var err error
+	if eo.Clusters, err = attrNumber(attr, "clusters", 1); err != nil {
+		return eo, err
+	}
+	if support, ok := attr["support"]; ok {
+		switch strings.ToLower(support) {
+		case "standard":
+			eo.Extended = false
+		case "extended":
+			eo.Extended = true
+		default:
+			return eo, fmt.Errorf("Unknown EKS support tier %s", support)
+		}
+	}
+	return eo, nil
+}
+
+func (eo EKSOffer) support() string {
+	if eo.Extended {
+		return "extended"
+	}
+	return "standard"
+}
+
+// String returns a simple string version of the pricing
+func (eo EKSOffer) String() string {
+	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo (%0.0f clusters, %s support)", eo.HourlyPrice(), eo.HourlyPrice()*HoursPerMonth, eo.Clusters, eo.support())
+}
+
+// Columns returns a slice of the column names for this type
+func (eo EKSOffer) Columns() []string {
+	return []string{"clusters", "support", "$/hr", "$/mo"}
+}
+
+// RowData returns data for this item for tablular presentation
+// Should be used in concert with Columns
+func (eo EKSOffer) RowData() []string {
+	return []string{fmt.Sprintf("%0.0f", eo.Clusters), eo.support(), fmt.Sprintf("$%0.3f", eo.HourlyPrice()), fmt.Sprintf("$%0.2f", eo.HourlyPrice()*HoursPerMonth)}
+}
@@ -0,0 +1,63 @@
+package awsprice
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractEKS(t *testing.T) {
+	db := extractFixture(t, eksExtractor)
+	if len(db.EKS) != 2 {
+		t.Errorf("Expected 2 EKS offers, got %d", len(db.EKS))
+	}
+	offer, err := db.Get(Query{Name: "eks"})
+	if err != nil {
+		t.Fatalf("Error getting EKS offer: %v", err)
+	}
+	// the Fargate usage in the EKS offer isn't a cluster rate
+	eks := offer.(EKSOffer)
+	if eks.Price != 0.10 || eks.ExtendedPrice != 0.60 {
+		t.Errorf("Expected standard 0.10 and extended 0.60, got %+v", eks)
+	}
+	offer, err = db.Get(Query{Name: "eks", Attr: map[string]string{"region": "eu-west-1"}})
+	if err != nil || offer.(EKSOffer).Price != 0.10 || offer.(EKSOffer).ExtendedPrice != 0 {
+		t.Errorf("Expected just a standard rate in eu-west-1, got %v (%v)", offer, err)
+	}
+	value, err := ParseInput(db, "eks(clusters=3, support=extended)")
+	if err != nil || !strings.HasPrefix(value, "$1.800 /hr") {
+		t.Errorf("Expected 3 extended support clusters at $1.800 /hr, got %q (%v)", value, err)
+	}
+}
+
+func TestEKSHourlyPrice(t *testing.T) {
+	offer := EKSOffer{Price: 0.10, ExtendedPrice: 0.60}
+	cases := []struct {
+		attr    map[string]string
+		support string
+		hourly  float64
+	}{
+		{map[string]string{}, "standard", 0.10},
+		{map[string]string{"clusters": "3"}, "standard", 0.30},
+		{map[string]string{"clusters": "3", "support": "extended"}, "extended", 1.80},
+		{map[string]string{"support": "Standard"}, "standard", 0.10},
+	}
+	for _, c := range cases {
+		sized, err := offer.withUsage(c.attr)
+		if err != nil {
+			t.Errorf("%v: error sizing offer: %v", c.attr, err)
+			continue
+		}
+		if !closeTo(sized.HourlyPrice(), c.hourly) {
+			t.Errorf("%v: expected $%v /hr, got $%v", c.attr, c.hourly, sized.HourlyPrice())
+		}
+		row := sized.RowData()
+		if len(row) != len(sized.Columns()) || row[1] != c.support {
+			t.Errorf("%v: unexpected row %v for %v", c.attr, row, sized.Columns())
+		}
+	}
+	for _, attr := range []map[string]string{{"support": "lts"}, {"clusters": "3GB"}} {
+		if _, err := offer.withUsage(attr); err == nil {
+			t.Errorf("%v: expected an error", attr)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package awsprice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fargateArchitectures and fargateOperatingSystems map the argument
+// spellings to the values stored in FargateOfferParam
+var fargateArchitectures = map[string]string{
+	"x86":    "x86_64",
+	"x86_64": "x86_64",
+	"amd64":  "x86_64",
+	"arm":    "arm64",
+	"arm64":  "arm64",
+}
+
+var fargateOperatingSystems = map[string]string{
+	"linux":   "Linux",
+	"windows": "Windows",
+}
+
+// FargateOffer The per vCPU-hour and GB-hour rates for Fargate tasks
+// of one architecture and OS. VCPU, MemoryGB and Tasks are filled in
+// from the request when the offer is looked up.
+type FargateOffer struct {
+	Location  string
+	Arch      string
+	OS        string
+	VCPUPrice float64
+	GBPrice   float64
+	// OSPrice is the per vCPU-hour license fee for Windows tasks
+	OSPrice  float64
+	VCPU     float64
+	MemoryGB float64
+	Tasks    float64
+}
+
+// Name returns the Fargate offer name
+func (fo FargateOffer) Name() string {
+	return "fargate"
+}
+
+// HourlyPrice returns the fractional dollars per hour for all tasks
+func (fo FargateOffer) HourlyPrice() float64 {
+	perTask := fo.VCPU*(fo.VCPUPrice+fo.OSPrice) + fo.MemoryGB*fo.GBPrice
+	return perTask * fo.Tasks
+}
+
+// Type always returns Fargate
+func (fo FargateOffer) Type() OfferType {
+	return Fargate
+}
+
+// FargateOfferParam stores the unique factors that determine a Fargate Offer
+type FargateOfferParam struct {
+	Region Region
+	Arch   string
+	OS     string
+}
+
+// NewFargateOfferParam constructs a Fargate offer from a name & attributes
+func NewFargateOfferParam(name string, attr map[string]string) (FargateOfferParam, error) {
+	offerParams := &FargateOfferParam{Arch: "x86_64", OS: "Linux"}
+	if region, ok := attr["region"]; ok {
+		reg, err := NewRegion(region)
+		if err != nil {
+			return *offerParams, err
+		}
+		offerParams.Region = reg
+	} else {
+		offerParams.Region = defaultRegion
+	}
+	if arch, ok := attr["arch"]; ok {
+		if offerParams.Arch, ok = fargateArchitectures[strings.ToLower(arch)]; !ok {
+			return *offerParams, fmt.Errorf("Unknown Fargate architecture %s", arch)
+		}
+	}
+	if os, ok := attr["os"]; ok {
+		if offerParams.OS, ok = fargateOperatingSystems[strings.ToLower(os)]; !ok {
+			return *offerParams, fmt.Errorf("Unknown Fargate OS %s", os)
+		}
+	}
+	return *offerParams, nil
+}
+
+// storeIn adds the offer to a PriceDB under its FargateOfferParam
+func (fo FargateOffer) storeIn(pd *PriceDB, key OfferKey) error {
+	param, err := NewFargateOfferParam(key.Name, key.Attr)
+	if err != nil {
+		return err
+	}
+	pd.register(key.Name, Fargate)
+	pd.Fargate[param] = fo
+	return nil
+}
+
+// getFargate looks up a Fargate offer, sized by the query's attributes
+// and at its savings plan rates if the query asks for a plan
+func (pd *PriceDB) getFargate(q Query) (Offer, error) {
+	param, err := NewFargateOfferParam(q.Name, q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	offer, ok := pd.Fargate[param]
+	if !ok {
+		return nil, &MissingError{What: "matching Fargate records"}
+	}
+	sized, err := offer.withUsage(q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := q.Attr["sp"]; ok {
+		return pd.fargateSavingsPlan(sized, param, q.Attr)
+	}
+	return sized, nil
+}
+
+// withUsage returns a copy of the offer sized by the vcpu, memory and
+// tasks attributes
+func (fo FargateOffer) withUsage(attr map[string]string) (FargateOffer, error) {
+	var err error
+	if fo.VCPU, err = attrNumber(attr, "vcpu", 1); err != nil {
+		return fo, err
+	}
+	if fo.MemoryGB, err = attrGB(attr, "memory", 2); err != nil {
+		return fo, err
+	}
+	if fo.Tasks, err = attrNumber(attr, "tasks", 1); err != nil {
+		return fo, err
+	}
+	return fo, nil
+}
+
+// String returns a simple string version of the pricing
+func (fo FargateOffer) String() string {
+	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo (%0.0f x %0.2g vCPU/%0.3g GB %s %s)", fo.HourlyPrice(), fo.HourlyPrice()*HoursPerMonth, fo.Tasks, fo.VCPU, fo.MemoryGB, fo.OS, fo.Arch)
+}
+
+// Columns returns a slice of the column names for this type
+func (fo FargateOffer) Columns() []string {
+	return []string{"OS", "Arch", "tasks", "vCPU", "GB", "$/vCPU-hr", "$/GB-hr", "$/hr", "$/mo"}
+}
+
+// RowData returns data for this item for tablular presentation
+// Should be used in concert with Columns
+func (fo FargateOffer) RowData() []string {
+	return []string{fo.OS, fo.Arch, fmt.Sprintf("%0.0f", fo.Tasks), fmt.Sprintf("%0.2g", fo.VCPU), fmt.Sprintf("%0.3g", fo.MemoryGB),
+		fmt.Sprintf("$%0.5f", fo.VCPUPrice+fo.OSPrice), fmt.Sprintf("$%0.5f", fo.GBPrice),
+		fmt.Sprintf("$%0.3f", fo.HourlyPrice()), fmt.Sprintf("$%0.2f", fo.HourlyPrice()*HoursPerMonth)}
+}
+
+// fargateUsage classifies an ECS usagetype such as
+// "USE1-Fargate-ARM-vCPU-Hours:perCPU" into its architecture, OS and
+// which rate it is (vcpu, gb or os). ok is false for anything else.
+func fargateUsage(usageType string) (arch string, os string, rate string, ok bool) {
+	if !strings.Contains(usageType, "Fargate-") {
+		return "", "", "", false
+	}
+	arch, os = "x86_64", "Linux"
+	if strings.Contains(usageType, "-ARM-") {
+		arch = "arm64"
+	}
+	if strings.Contains(usageType, "-Windows-") {
+		os = "Windows"
+	}
+	switch {
+	case strings.Contains(usageType, "-OS-Hours"):
+		rate = "os"
+	case strings.Contains(usageType, "-vCPU-Hours"):
+		rate = "vcpu"
+	case strings.Contains(usageType, "-GB-Hours"):
+		rate = "gb"
+	default:
+		return "", "", "", false
+	}
+	// ephemeral storage and spot are billed under similar names
+	if strings.Contains(usageType, "Spot") || strings.Contains(usageType, "EphemeralStorage") {
+		return "", "", "", false
+	}
+	return arch, os, rate, true
+}
@@ -0,0 +1,33 @@
+package awsprice
+
+import "testing"
+
+func TestFargateUsage(t *testing.T) {
+	cases := map[string][3]string{
+		"USE1-Fargate-vCPU-Hours:perCPU":         {"x86_64", "Linux", "vcpu"},
+		"USE1-Fargate-ARM-GB-Hours":              {"arm64", "Linux", "gb"},
+		"USE1-Fargate-Windows-OS-Hours:perCPU":   {"x86_64", "Windows", "os"},
+		"USE1-Fargate-Windows-vCPU-Hours:perCPU": {"x86_64", "Windows", "vcpu"},
+	}
+	for usage, expected := range cases {
+		arch, os, rate, ok := fargateUsage(usage)
+		if !ok || arch != expected[0] || os != expected[1] || rate != expected[2] {
+			t.Errorf("%s: expected %v, got %s %s %s (%v)", usage, expected, arch, os, rate, ok)
+		}
+	}
+	if _, _, _, ok := fargateUsage("USE1-Fargate-EphemeralStorage-GB-Hours"); ok {
+		t.Error("Expected ephemeral storage to be skipped")
+	}
+}
+
+func TestFargateHourlyPrice(t *testing.T) {
+	offer := FargateOffer{VCPUPrice: 0.04, GBPrice: 0.004}
+	offer, err := offer.withUsage(map[string]string{"vcpu": "2", "memory": "4GB", "tasks": "10"})
+	if err != nil {
+		t.Fatalf("Error sizing offer: %v", err)
+	}
+	expected := 10 * (2*0.04 + 4*0.004)
+	if got := offer.HourlyPrice(); got-expected > 1e-9 || expected-got > 1e-9 {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
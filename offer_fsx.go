@@ -0,0 +1,181 @@
+package awsprice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FSxAttr identifies a selected list of useful attributes
+type FSxAttr struct {
+	ServiceCode        string `json:"servicecode"`
+	Location           string `json:"location"`
+	LocationType       string `json:"locationType"`
+	FileSystemType     string `json:"fileSystemType"`
+	DeploymentOption   string `json:"deploymentOption"`
+	StorageType        string `json:"storageType"`
+	ThroughputCapacity string `json:"throughputCapacity"`
+	UsageType          string `json:"usagetype"`
+}
+
+// fsxFileSystems maps the offer names to the fileSystemType used in
+// the offer file
+var fsxFileSystems = map[string]string{
+	"fsx.lustre":  "Lustre",
+	"fsx.windows": "Windows",
+	"fsx.ontap":   "ONTAP",
+}
+
+// FSxOffer The product/price details for a given FSx file system
+// configuration. Price is per GB-month of storage and ThroughputPrice
+// per MB/s-month of provisioned throughput capacity (zero for Lustre,
+// where throughput is bundled into the storage price).
+type FSxOffer struct {
+	Product         FSxAttr
+	Price           float64
+	ThroughputPrice float64
+	Size            float64
+	Throughput      float64
+}
+
+// Name returns the FSx offer name, such as fsx.lustre
+func (fo FSxOffer) Name() string {
+	for name, fsType := range fsxFileSystems {
+		if fsType == fo.Product.FileSystemType {
+			return name
+		}
+	}
+	return "fsx"
+}
+
+// MonthlyPrice returns the dollars per month for the configured capacity
+func (fo FSxOffer) MonthlyPrice() float64 {
+	return fo.Size*fo.Price + fo.Throughput*fo.ThroughputPrice
+}
+
+// HourlyPrice returns the fractional dollars per hour
+func (fo FSxOffer) HourlyPrice() float64 {
+	return fo.MonthlyPrice() / HoursPerMonth
+}
+
+// Type always returns FSx
+func (fo FSxOffer) Type() OfferType {
+	return FSx
+}
+
+// FSxOfferParam stores the unique factors that determine an FSx Offer
+type FSxOfferParam struct {
+	Region             Region
+	FileSystemType     string
+	DeploymentOption   string
+	StorageType        string
+	ThroughputCapacity string
+}
+
+// NewFSxOfferParam constructs an FSx offer from a name & attributes
+func NewFSxOfferParam(name string, attr map[string]string) (FSxOfferParam, error) {
+	offerParams := &FSxOfferParam{}
+	fsType, ok := fsxFileSystems[name]
+	if !ok {
+		return *offerParams, fmt.Errorf("Unknown FSx file system %s", name)
+	}
+	offerParams.FileSystemType = fsType
+	if region, ok := attr["region"]; ok {
+		reg, err := NewRegion(region)
+		if err != nil {
+			return *offerParams, err
+		}
+		offerParams.Region = reg
+	} else {
+		offerParams.Region = defaultRegion
+	}
+	if deployment, ok := attr["deployment"]; ok {
+		offerParams.DeploymentOption = deployment
+	} else if fsType == "Windows" {
+		offerParams.DeploymentOption = "Multi-AZ"
+	} else {
+		offerParams.DeploymentOption = "Single-AZ"
+	}
+	if storage, ok := attr["storage"]; ok {
+		offerParams.StorageType = strings.ToUpper(storage)
+	} else {
+		offerParams.StorageType = "SSD"
+	}
+	// Lustre storage is priced by the throughput per TiB it provides
+	if fsType == "Lustre" {
+		if perUnit, ok := attr["perunit"]; ok {
+			offerParams.ThroughputCapacity = perUnit
+		} else {
+			offerParams.ThroughputCapacity = "125"
+		}
+	}
+	return *offerParams, nil
+}
+
+// storeIn adds the offer to a PriceDB under its FSxOfferParam
+func (fo FSxOffer) storeIn(pd *PriceDB, key OfferKey) error {
+	param, err := NewFSxOfferParam(key.Name, key.Attr)
+	if err != nil {
+		return err
+	}
+	pd.register(key.Name, FSx)
+	pd.FSx[param] = fo
+	return nil
+}
+
+// getFSx looks up an FSx offer, sized by the query's attributes
+func (pd *PriceDB) getFSx(q Query) (Offer, error) {
+	param, err := NewFSxOfferParam(q.Name, q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	offer, ok := pd.FSx[param]
+	if !ok {
+		return nil, &MissingError{What: "matching FSx records"}
+	}
+	sized, err := offer.withUsage(q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	return sized, nil
+}
+
+// fsxPerUnitThroughput normalises a Lustre throughputCapacity such as
+// "125 MB/s/TiB" to the bare number used in FSxOfferParam
+func fsxPerUnitThroughput(given string) string {
+	if fields := strings.Fields(given); len(fields) > 0 {
+		return fields[0]
+	}
+	return given
+}
+
+// withUsage returns a copy of the offer sized by the size and
+// throughput attributes
+func (fo FSxOffer) withUsage(attr map[string]string) (FSxOffer, error) {
+	var err error
+	if fo.Size, err = attrGB(attr, "size", 1024); err != nil {
+		return fo, err
+	}
+	if fo.ThroughputPrice == 0 {
+		return fo, nil
+	}
+	if fo.Throughput, err = attrMBps(attr, "throughput", 128); err != nil {
+		return fo, err
+	}
+	return fo, nil
+}
+
+// String returns a simple string version of the pricing
+func (fo FSxOffer) String() string {
+	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo (%0.0f GB %s %s)", fo.HourlyPrice(), fo.MonthlyPrice(), fo.Size, fo.Product.StorageType, fo.Product.DeploymentOption)
+}
+
+// Columns returns a slice of the column names for this type
+func (fo FSxOffer) Columns() []string {
+	return []string{"type", "Deployment", "Storage", "GB", "MB/s", "$/hr", "$/mo"}
+}
+
+// RowData returns data for this item for tablular presentation
+// Should be used in concert with Columns
+func (fo FSxOffer) RowData() []string {
+	return []string{fo.Name(), fo.Product.DeploymentOption, fo.Product.StorageType, fmt.Sprintf("%0.0f", fo.Size), fmt.Sprintf("%0.0f", fo.Throughput), fmt.Sprintf("$%0.3f", fo.HourlyPrice()), fmt.Sprintf("$%0.2f", fo.MonthlyPrice())}
+}
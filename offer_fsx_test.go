@@ -0,0 +1,78 @@
+package awsprice
+
+import "testing"
+
+func TestExtractFSx(t *testing.T) {
+	db := extractFixture(t, fsxExtractor)
+	// OpenZFS isn't one of the supported file systems
+	if len(db.FSx) != 4 {
+		t.Errorf("Expected 4 FSx offers, got %d", len(db.FSx))
+	}
+	cases := []struct {
+		name       string
+		attr       map[string]string
+		price      float64
+		throughput float64
+	}{
+		// Lustre throughput is bundled into the storage price
+		{"fsx.lustre", map[string]string{}, 0.145, 0},
+		{"fsx.lustre", map[string]string{"perunit": "250"}, 0.21, 0},
+		{"fsx.windows", map[string]string{}, 0.23, 4.5},
+		{"fsx.ontap", map[string]string{"storage": "ssd"}, 0.125, 1.2},
+	}
+	for _, c := range cases {
+		offer, err := db.Get(Query{Name: c.name, Attr: c.attr})
+		if err != nil {
+			t.Errorf("%s%v: error getting FSx offer: %v", c.name, c.attr, err)
+			continue
+		}
+		fsx := offer.(FSxOffer)
+		if fsx.Price != c.price || fsx.ThroughputPrice != c.throughput || fsx.Name() != c.name {
+			t.Errorf("%s%v: expected %v and %v, got %+v", c.name, c.attr, c.price, c.throughput, fsx)
+		}
+	}
+	if _, err := db.Get(Query{Name: "fsx.lustre", Attr: map[string]string{"perunit": "1000"}}); err == nil {
+		t.Error("Expected no 1000 MB/s/TiB Lustre offer in the fixture")
+	}
+	if _, err := db.Get(Query{Name: "fsx.windows", Attr: map[string]string{"deployment": "Single-AZ"}}); err == nil {
+		t.Error("Expected no Single-AZ Windows offer in the fixture")
+	}
+}
+
+func TestFSxMonthlyPrice(t *testing.T) {
+	lustre := FSxOffer{Product: FSxAttr{FileSystemType: "Lustre"}, Price: 0.145}
+	windows := FSxOffer{Product: FSxAttr{FileSystemType: "Windows"}, Price: 0.23, ThroughputPrice: 4.5}
+	cases := []struct {
+		offer      FSxOffer
+		attr       map[string]string
+		throughput float64
+		monthly    float64
+	}{
+		{lustre, map[string]string{}, 0, 1024 * 0.145},
+		{lustre, map[string]string{"size": "2.4TB", "throughput": "500MBps"}, 0, 2.4 * 1024 * 0.145},
+		{windows, map[string]string{}, 128, 1024*0.23 + 128*4.5},
+		{windows, map[string]string{"size": "500GB", "throughput": "32MBps"}, 32, 500*0.23 + 32*4.5},
+	}
+	for _, c := range cases {
+		sized, err := c.offer.withUsage(c.attr)
+		if err != nil {
+			t.Errorf("%s%v: error sizing offer: %v", c.offer.Name(), c.attr, err)
+			continue
+		}
+		if sized.Throughput != c.throughput || !closeTo(sized.MonthlyPrice(), c.monthly) {
+			t.Errorf("%s%v: expected %v MB/s at $%v /mo, got %v at $%v", c.offer.Name(), c.attr, c.throughput, c.monthly, sized.Throughput, sized.MonthlyPrice())
+		}
+		if !closeTo(sized.HourlyPrice(), c.monthly/HoursPerMonth) {
+			t.Errorf("%s%v: expected $%v /hr, got $%v", c.offer.Name(), c.attr, c.monthly/HoursPerMonth, sized.HourlyPrice())
+		}
+		if len(sized.RowData()) != len(sized.Columns()) || sized.RowData()[0] != c.offer.Name() {
+			t.Errorf("%s%v: unexpected row %v for %v", c.offer.Name(), c.attr, sized.RowData(), sized.Columns())
+		}
+	}
+	if _, err := windows.withUsage(map[string]string{"throughput": "fast"}); err == nil {
+		t.Error("Expected an error for an unparseable throughput")
+	}
+	if fsxPerUnitThroughput("125 MB/s/TiB") != "125" {
+		t.Errorf("Expected 125, got %s", fsxPerUnitThroughput("125 MB/s/TiB"))
+	}
+}
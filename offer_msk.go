@@ -0,0 +1,136 @@
+package awsprice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MSKAttr identifies a selected list of useful attributes
+type MSKAttr struct {
+	ServiceCode  string `json:"servicecode"`
+	Location     string `json:"location"`
+	LocationType string `json:"locationType"`
+	InstanceType string `json:"instanceType"`
+	VCPU         string `json:"vcpu"`
+	Memory       string `json:"memory"`
+	UsageType    string `json:"usagetype"`
+}
+
+// MSKOffer The product/price details for an MSK broker instance type.
+// StoragePrice is the per GB-month broker storage rate; Brokers and
+// StorageGB (per broker) are filled in from the request when looked up.
+type MSKOffer struct {
+	Product      MSKAttr
+	Price        float64
+	StoragePrice float64
+	Brokers      float64
+	StorageGB    float64
+}
+
+// Name returns the MSK broker type, like kafka.m5.large
+func (mo MSKOffer) Name() string {
+	return mo.Product.InstanceType
+}
+
+// HourlyPrice returns the fractional dollars per hour for all brokers
+// and their storage
+func (mo MSKOffer) HourlyPrice() float64 {
+	return mo.Brokers * (mo.Price + mo.StorageGB*mo.StoragePrice/HoursPerMonth)
+}
+
+// Type always returns MSK
+func (mo MSKOffer) Type() OfferType {
+	return MSK
+}
+
+// MSKOfferParam stores the unique factors that determine an MSK Offer
+type MSKOfferParam struct {
+	Region Region
+	Name   string
+}
+
+// NewMSKOfferParam constructs an MSK offer from a name & attributes
+func NewMSKOfferParam(name string, attr map[string]string) (MSKOfferParam, error) {
+	offerParams := &MSKOfferParam{Name: name}
+	if region, ok := attr["region"]; ok {
+		reg, err := NewRegion(region)
+		if err != nil {
+			return *offerParams, err
+		}
+		offerParams.Region = reg
+	} else {
+		offerParams.Region = defaultRegion
+	}
+	return *offerParams, nil
+}
+
+// storeIn adds the offer to a PriceDB under its MSKOfferParam
+func (mo MSKOffer) storeIn(pd *PriceDB, key OfferKey) error {
+	param, err := NewMSKOfferParam(key.Name, key.Attr)
+	if err != nil {
+		return err
+	}
+	pd.register(key.Name, MSK)
+	pd.MSK[param] = mo
+	return nil
+}
+
+// getMSK looks up an MSK offer, sized by the query's attributes
+func (pd *PriceDB) getMSK(q Query) (Offer, error) {
+	param, err := NewMSKOfferParam(q.Name, q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	offer, ok := pd.MSK[param]
+	if !ok {
+		return nil, &MissingError{What: "matching MSK records"}
+	}
+	sized, err := offer.withUsage(q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	return sized, nil
+}
+
+// mskBrokerType returns the broker type for an MSK product, falling
+// back to the usagetype (like "USE1-Kafka.m5.large") when the
+// instanceType attribute is missing. Serverless usage, like
+// "USE1-Kafka.Serverless.ClusterHours", isn't a broker.
+func mskBrokerType(attr MSKAttr) string {
+	if attr.InstanceType != "" {
+		return attr.InstanceType
+	}
+	usage := strings.ToLower(attr.UsageType)
+	if i := strings.Index(usage, "kafka."); i != -1 && !strings.Contains(usage, "serverless") {
+		return usage[i:]
+	}
+	return ""
+}
+
+// withUsage returns a copy of the offer for the brokers and storage attributes
+func (mo MSKOffer) withUsage(attr map[string]string) (MSKOffer, error) {
+	var err error
+	if mo.Brokers, err = attrNumber(attr, "brokers", 3); err != nil {
+		return mo, err
+	}
+	if mo.StorageGB, err = attrGB(attr, "storage", 0); err != nil {
+		return mo, err
+	}
+	return mo, nil
+}
+
+// String returns a simple string version of the pricing
+func (mo MSKOffer) String() string {
+	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo", mo.HourlyPrice(), mo.HourlyPrice()*HoursPerMonth)
+}
+
+// Columns returns a slice of the column names for this type
+func (mo MSKOffer) Columns() []string {
+	return []string{"type", "vCPU", "Mem", "brokers", "GB/broker", "$/hr", "$/mo"}
+}
+
+// RowData returns data for this item for tablular presentation
+// Should be used in concert with Columns
+func (mo MSKOffer) RowData() []string {
+	return []string{mo.Name(), mo.Product.VCPU, mo.Product.Memory, fmt.Sprintf("%0.0f", mo.Brokers), fmt.Sprintf("%0.0f", mo.StorageGB), fmt.Sprintf("$%0.3f", mo.HourlyPrice()), fmt.Sprintf("$%0.2f", mo.HourlyPrice()*HoursPerMonth)}
+}
@@ -0,0 +1,67 @@
+package awsprice
+
+import "testing"
+
+func TestMSKBrokerType(t *testing.T) {
+	cases := map[MSKAttr]string{
+		{InstanceType: "kafka.m5.large", UsageType: "USW2-Kafka.m5.large"}: "kafka.m5.large",
+		{UsageType: "USW2-Kafka.m7g.large"}:                                "kafka.m7g.large",
+		{UsageType: "USW2-Kafka.Serverless.ClusterHours"}:                  "",
+		{UsageType: "USW2-DataTransfer-Out-Bytes"}:                         "",
+	}
+	for attr, expected := range cases {
+		if got := mskBrokerType(attr); got != expected {
+			t.Errorf("%+v: expected %q, got %q", attr, expected, got)
+		}
+	}
+}
+
+func TestExtractMSK(t *testing.T) {
+	db := extractFixture(t, mskExtractor)
+	// serverless clusters aren't brokers
+	if len(db.MSK) != 2 {
+		t.Errorf("Expected 2 MSK offers, got %d", len(db.MSK))
+	}
+	// every broker gets the region's storage price
+	for name, price := range map[string]float64{"kafka.m5.large": 0.21, "kafka.m7g.large": 0.204} {
+		offer, err := db.Get(Query{Name: name})
+		if err != nil {
+			t.Errorf("%s: error getting offer: %v", name, err)
+			continue
+		}
+		msk := offer.(MSKOffer)
+		if msk.Price != price || msk.StoragePrice != 0.10 || msk.Name() != name {
+			t.Errorf("%s: expected %v with storage at 0.10, got %+v", name, price, msk)
+		}
+	}
+}
+
+func TestMSKHourlyPrice(t *testing.T) {
+	offer := MSKOffer{Product: MSKAttr{InstanceType: "kafka.m5.large"}, Price: 0.21, StoragePrice: 0.10}
+	cases := []struct {
+		attr   map[string]string
+		hourly float64
+	}{
+		{map[string]string{}, 3 * 0.21},
+		{map[string]string{"brokers": "6"}, 6 * 0.21},
+		{map[string]string{"brokers": "6", "storage": "1000GB"}, 6 * (0.21 + 1000*0.10/HoursPerMonth)},
+	}
+	for _, c := range cases {
+		sized, err := offer.withUsage(c.attr)
+		if err != nil {
+			t.Errorf("%v: error sizing offer: %v", c.attr, err)
+			continue
+		}
+		if !closeTo(sized.HourlyPrice(), c.hourly) {
+			t.Errorf("%v: expected $%v /hr, got $%v", c.attr, c.hourly, sized.HourlyPrice())
+		}
+		if row := sized.RowData(); len(row) != len(sized.Columns()) || row[0] != "kafka.m5.large" {
+			t.Errorf("%v: unexpected row %v for %v", c.attr, row, sized.Columns())
+		}
+	}
+	for _, attr := range []map[string]string{{"brokers": "many"}, {"storage": "1000 bananas"}} {
+		if _, err := offer.withUsage(attr); err == nil {
+			t.Errorf("%v: expected an error", attr)
+		}
+	}
+}
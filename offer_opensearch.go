@@ -0,0 +1,111 @@
+package awsprice
+
+import "fmt"
+
+// OpenSearchAttr identifies a selected list of useful attributes
+type OpenSearchAttr struct {
+	ServiceCode  string `json:"servicecode"`
+	Location     string `json:"location"`
+	LocationType string `json:"locationType"`
+	InstanceType string `json:"instanceType"`
+	VCPU         string `json:"vcpu"`
+	Memory       string `json:"memoryGib"`
+	Storage      string `json:"storage"`
+	UsageType    string `json:"usagetype"`
+}
+
+// OpenSearchOffer The product/price details for an OpenSearch Service
+// instance type. Nodes is filled in from the request when looked up.
+type OpenSearchOffer struct {
+	Product OpenSearchAttr
+	Price   float64
+	Nodes   float64
+}
+
+// Name returns the OpenSearch instance type, like r6g.large.search
+func (oo OpenSearchOffer) Name() string {
+	return oo.Product.InstanceType
+}
+
+// HourlyPrice returns the fractional dollars per hour for all nodes
+func (oo OpenSearchOffer) HourlyPrice() float64 {
+	return oo.Price * oo.Nodes
+}
+
+// Type always returns OpenSearch
+func (oo OpenSearchOffer) Type() OfferType {
+	return OpenSearch
+}
+
+// OpenSearchOfferParam stores the unique factors that determine an
+// OpenSearch Offer
+type OpenSearchOfferParam struct {
+	Region Region
+	Name   string
+}
+
+// NewOpenSearchOfferParam constructs an OpenSearch offer from a name & attributes
+func NewOpenSearchOfferParam(name string, attr map[string]string) (OpenSearchOfferParam, error) {
+	offerParams := &OpenSearchOfferParam{Name: name}
+	if region, ok := attr["region"]; ok {
+		reg, err := NewRegion(region)
+		if err != nil {
+			return *offerParams, err
+		}
+		offerParams.Region = reg
+	} else {
+		offerParams.Region = defaultRegion
+	}
+	return *offerParams, nil
+}
+
+// storeIn adds the offer to a PriceDB under its OpenSearchOfferParam
+func (oo OpenSearchOffer) storeIn(pd *PriceDB, key OfferKey) error {
+	param, err := NewOpenSearchOfferParam(key.Name, key.Attr)
+	if err != nil {
+		return err
+	}
+	pd.register(key.Name, OpenSearch)
+	pd.OpenSearch[param] = oo
+	return nil
+}
+
+// getOpenSearch looks up an OpenSearch offer, sized by the query's attributes
+func (pd *PriceDB) getOpenSearch(q Query) (Offer, error) {
+	param, err := NewOpenSearchOfferParam(q.Name, q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	offer, ok := pd.OpenSearch[param]
+	if !ok {
+		return nil, &MissingError{What: "matching OpenSearch records"}
+	}
+	sized, err := offer.withUsage(q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	return sized, nil
+}
+
+// withUsage returns a copy of the offer for the nodes attribute
+func (oo OpenSearchOffer) withUsage(attr map[string]string) (OpenSearchOffer, error) {
+	var err error
+	oo.Nodes, err = attrNumber(attr, "nodes", 1)
+	return oo, err
+}
+
+// String returns a simple string version of the pricing
+func (oo OpenSearchOffer) String() string {
+	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo", oo.HourlyPrice(), oo.HourlyPrice()*HoursPerMonth)
+}
+
+// Columns returns a slice of the column names for this type
+func (oo OpenSearchOffer) Columns() []string {
+	return []string{"type", "vCPU", "Mem", "Storage", "nodes", "$/hr", "$/mo"}
+}
+
+// RowData returns data for this item for tablular presentation
+// Should be used in concert with Columns
+func (oo OpenSearchOffer) RowData() []string {
+	return []string{oo.Product.InstanceType, oo.Product.VCPU, oo.Product.Memory, oo.Product.Storage, fmt.Sprintf("%0.0f", oo.Nodes), fmt.Sprintf("$%0.3f", oo.HourlyPrice()), fmt.Sprintf("$%0.2f", oo.HourlyPrice()*HoursPerMonth)}
+}
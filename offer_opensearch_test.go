@@ -0,0 +1,48 @@
+package awsprice
+
+import "testing"
+
+func TestExtractOpenSearch(t *testing.T) {
+	db := extractFixture(t, openSearchExtractor)
+	// legacy .elasticsearch instances and EBS volumes aren't extracted
+	if len(db.OpenSearch) != 2 {
+		t.Errorf("Expected 2 OpenSearch offers, got %d", len(db.OpenSearch))
+	}
+	for region, price := range map[string]float64{"us-west-2": 0.167, "eu-west-1": 0.186} {
+		offer, err := db.Get(Query{Name: "r6g.large.search", Attr: map[string]string{"region": region}})
+		if err != nil || offer.(OpenSearchOffer).Price != price {
+			t.Errorf("%s: expected %v, got %v (%v)", region, price, offer, err)
+			continue
+		}
+		if product := offer.(OpenSearchOffer).Product; product.VCPU != "2" || product.Memory != "16" {
+			t.Errorf("%s: unexpected product %+v", region, product)
+		}
+	}
+	if _, err := db.Get(Query{Name: "m4.large.elasticsearch"}); err == nil {
+		t.Error("Expected no offer for m4.large.elasticsearch")
+	}
+}
+
+func TestOpenSearchHourlyPrice(t *testing.T) {
+	offer := OpenSearchOffer{Product: OpenSearchAttr{InstanceType: "r6g.large.search"}, Price: 0.167}
+	for nodes, hourly := range map[string]float64{"": 0.167, "3": 0.501} {
+		attr := map[string]string{}
+		if nodes != "" {
+			attr["nodes"] = nodes
+		}
+		sized, err := offer.withUsage(attr)
+		if err != nil {
+			t.Errorf("%v: error sizing offer: %v", attr, err)
+			continue
+		}
+		if !closeTo(sized.HourlyPrice(), hourly) {
+			t.Errorf("%v: expected $%v /hr, got $%v", attr, hourly, sized.HourlyPrice())
+		}
+		if row := sized.RowData(); len(row) != len(sized.Columns()) || row[0] != "r6g.large.search" {
+			t.Errorf("%v: unexpected row %v for %v", attr, row, sized.Columns())
+		}
+	}
+	if _, err := offer.withUsage(map[string]string{"nodes": "three"}); err == nil {
+		t.Error("Expected an error for an unparseable node count")
+	}
+}
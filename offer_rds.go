@@ -2,22 +2,6 @@ package awsprice
 
 import "fmt"
 
-// RDSOfferIndex is at the root of the RDS Offer JSON document
-type RDSOfferIndex struct {
-	FormatVersion   string                `json:"formatVersion"`
-	Disclaimer      string                `json:"disclaimer"`
-	PublicationDate string                `json:"publicationDate"`
-	Products        map[string]RDSProduct `json:"products"`
-	Terms           RDSTerms              `json:"terms"`
-}
-
-// RDSProduct identifies a single product 'leaf' in the JSON document
-type RDSProduct struct {
-	SKU           string  `json:"sku"`
-	ProductFamily string  `json:"productFamily"`
-	Attr          RDSAttr `json:"attributes"`
-}
-
 // RDSAttr identifies a selected list of useful attributes
 type RDSAttr struct {
 	ServiceCode       string `json:"servicecode"`
@@ -34,27 +18,6 @@ type RDSAttr struct {
 	DeploymentOption  string `json:"deploymentOption"`
 }
 
-// RDSTerms tracks the various terms. For now only OnDemand (not prepaid/spot/etc) is used.
-type RDSTerms struct {
-	OnDemand map[string]map[string]RDSTermItem
-}
-
-// RDSTermItem is a given pricing term
-type RDSTermItem struct {
-	OfferTermCode   string                        `json:"offerTermCode"`
-	SKU             string                        `json:"sku"`
-	PriceDimensions map[string]RDSPriceDimensions `json:"priceDimensions"`
-}
-
-// RDSPriceDimensions stores various combinations of billing duration
-// and currency
-type RDSPriceDimensions struct {
-	RateCode     string            `json:"rateCode"`
-	Description  string            `json:"description"`
-	Unit         string            `json:"unit"`
-	PricePerUnit map[string]string `json:"pricePerUnit"`
-}
-
 // RDSOffer The product/price details for a given RDS Offering
 type RDSOffer struct {
 	Product RDSAttr
@@ -109,6 +72,30 @@ func NewRDSOfferParam(name string, attr map[string]string) (RDSOfferParam, error
 	return *offerParams, nil
 }
 
+// storeIn adds the offer to a PriceDB under its RDSOfferParam
+func (ro RDSOffer) storeIn(pd *PriceDB, key OfferKey) error {
+	param, err := NewRDSOfferParam(key.Name, key.Attr)
+	if err != nil {
+		return err
+	}
+	pd.register(key.Name, RDS)
+	pd.RDS[param] = ro
+	return nil
+}
+
+// getRDS looks up an RDS offer
+func (pd *PriceDB) getRDS(q Query) (Offer, error) {
+	param, err := NewRDSOfferParam(q.Name, q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	offer, ok := pd.RDS[param]
+	if !ok {
+		return nil, &MissingError{What: "matching RDS records"}
+	}
+	return offer, nil
+}
+
 // String returns a simple string version of the pricing
 func (ro RDSOffer) String() string {
 	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo", ro.HourlyPrice(), ro.HourlyPrice()*HoursPerMonth)
@@ -0,0 +1,120 @@
+package awsprice
+
+import "fmt"
+
+// RedshiftAttr identifies a selected list of useful attributes
+type RedshiftAttr struct {
+	ServiceCode  string `json:"servicecode"`
+	Location     string `json:"location"`
+	LocationType string `json:"locationType"`
+	InstanceType string `json:"instanceType"`
+	VCPU         string `json:"vcpu"`
+	Memory       string `json:"memory"`
+	Storage      string `json:"storage"`
+	UsageType    string `json:"usagetype"`
+}
+
+// RedshiftOffer The product/price details for a Redshift node type.
+// StoragePrice is the per GB-month Redshift Managed Storage rate, which
+// applies to RA3 nodes; Nodes and StorageGB are filled in from the
+// request when looked up.
+type RedshiftOffer struct {
+	Product      RedshiftAttr
+	Price        float64
+	StoragePrice float64
+	Nodes        float64
+	StorageGB    float64
+}
+
+// Name returns the Redshift node type
+func (ro RedshiftOffer) Name() string {
+	return ro.Product.InstanceType
+}
+
+// HourlyPrice returns the fractional dollars per hour for all nodes
+// and managed storage
+func (ro RedshiftOffer) HourlyPrice() float64 {
+	return ro.Price*ro.Nodes + ro.StorageGB*ro.StoragePrice/HoursPerMonth
+}
+
+// Type always returns Redshift
+func (ro RedshiftOffer) Type() OfferType {
+	return Redshift
+}
+
+// RedshiftOfferParam stores the unique factors that determine a Redshift Offer
+type RedshiftOfferParam struct {
+	Region Region
+	Name   string
+}
+
+// NewRedshiftOfferParam constructs a Redshift offer from a name & attributes
+func NewRedshiftOfferParam(name string, attr map[string]string) (RedshiftOfferParam, error) {
+	offerParams := &RedshiftOfferParam{Name: name}
+	if region, ok := attr["region"]; ok {
+		reg, err := NewRegion(region)
+		if err != nil {
+			return *offerParams, err
+		}
+		offerParams.Region = reg
+	} else {
+		offerParams.Region = defaultRegion
+	}
+	return *offerParams, nil
+}
+
+// storeIn adds the offer to a PriceDB under its RedshiftOfferParam
+func (ro RedshiftOffer) storeIn(pd *PriceDB, key OfferKey) error {
+	param, err := NewRedshiftOfferParam(key.Name, key.Attr)
+	if err != nil {
+		return err
+	}
+	pd.register(key.Name, Redshift)
+	pd.Redshift[param] = ro
+	return nil
+}
+
+// getRedshift looks up a Redshift offer, sized by the query's attributes
+func (pd *PriceDB) getRedshift(q Query) (Offer, error) {
+	param, err := NewRedshiftOfferParam(q.Name, q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	offer, ok := pd.Redshift[param]
+	if !ok {
+		return nil, &MissingError{What: "matching Redshift records"}
+	}
+	sized, err := offer.withUsage(q.Attr)
+	if err != nil {
+		return nil, err
+	}
+	return sized, nil
+}
+
+// withUsage returns a copy of the offer for the nodes and storage attributes
+func (ro RedshiftOffer) withUsage(attr map[string]string) (RedshiftOffer, error) {
+	var err error
+	if ro.Nodes, err = attrNumber(attr, "nodes", 1); err != nil {
+		return ro, err
+	}
+	if ro.StorageGB, err = attrGB(attr, "storage", 0); err != nil {
+		return ro, err
+	}
+	return ro, nil
+}
+
+// String returns a simple string version of the pricing
+func (ro RedshiftOffer) String() string {
+	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo", ro.HourlyPrice(), ro.HourlyPrice()*HoursPerMonth)
+}
+
+// Columns returns a slice of the column names for this type
+func (ro RedshiftOffer) Columns() []string {
+	return []string{"type", "vCPU", "Mem", "nodes", "Storage GB", "$/hr", "$/mo"}
+}
+
+// RowData returns data for this item for tablular presentation
+// Should be used in concert with Columns
+func (ro RedshiftOffer) RowData() []string {
+	return []string{ro.Product.InstanceType, ro.Product.VCPU, ro.Product.Memory, fmt.Sprintf("%0.0f", ro.Nodes), fmt.Sprintf("%0.0f", ro.StorageGB), fmt.Sprintf("$%0.3f", ro.HourlyPrice()), fmt.Sprintf("$%0.2f", ro.HourlyPrice()*HoursPerMonth)}
+}
@@ -0,0 +1,53 @@
+package awsprice
+
+import "testing"
+
+func TestExtractRedshift(t *testing.T) {
+	db := extractFixture(t, redshiftExtractor)
+	// the managed storage and Spectrum products aren't nodes
+	if len(db.Redshift) != 2 {
+		t.Errorf("Expected 2 Redshift offers, got %d", len(db.Redshift))
+	}
+	// only RA3 nodes use managed storage
+	for name, expected := range map[string][2]float64{"ra3.xlplus": {1.086, 0.024}, "dc2.large": {0.25, 0}} {
+		offer, err := db.Get(Query{Name: name})
+		if err != nil {
+			t.Errorf("%s: error getting offer: %v", name, err)
+			continue
+		}
+		redshift := offer.(RedshiftOffer)
+		if redshift.Price != expected[0] || redshift.StoragePrice != expected[1] {
+			t.Errorf("%s: expected %v, got %v and %v", name, expected, redshift.Price, redshift.StoragePrice)
+		}
+	}
+}
+
+func TestRedshiftHourlyPrice(t *testing.T) {
+	offer := RedshiftOffer{Product: RedshiftAttr{InstanceType: "ra3.xlplus"}, Price: 1.086, StoragePrice: 0.024}
+	cases := []struct {
+		attr   map[string]string
+		hourly float64
+	}{
+		{map[string]string{}, 1.086},
+		{map[string]string{"nodes": "2"}, 2 * 1.086},
+		{map[string]string{"nodes": "2", "storage": "1TB"}, 2*1.086 + 1024*0.024/HoursPerMonth},
+	}
+	for _, c := range cases {
+		sized, err := offer.withUsage(c.attr)
+		if err != nil {
+			t.Errorf("%v: error sizing offer: %v", c.attr, err)
+			continue
+		}
+		if !closeTo(sized.HourlyPrice(), c.hourly) {
+			t.Errorf("%v: expected $%v /hr, got $%v", c.attr, c.hourly, sized.HourlyPrice())
+		}
+		if row := sized.RowData(); len(row) != len(sized.Columns()) || row[0] != "ra3.xlplus" {
+			t.Errorf("%v: unexpected row %v for %v", c.attr, row, sized.Columns())
+		}
+	}
+	for _, attr := range []map[string]string{{"nodes": "2.5TB"}, {"storage": "lots"}} {
+		if _, err := offer.withUsage(attr); err == nil {
+			t.Errorf("%v: expected an error", attr)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package awsprice
+
+import "fmt"
+
+// SavingsPlanOffer is an offer priced at a savings plan's discounted rate
+type SavingsPlanOffer struct {
+	Base  Offer
+	Price float64
+	Key   SavingsPlanKey
+}
+
+// Name returns the name of the discounted offer
+func (so SavingsPlanOffer) Name() string {
+	return so.Base.Name()
+}
+
+// HourlyPrice returns the discounted dollars per hour, which is also
+// the hourly commitment that covers this usage
+func (so SavingsPlanOffer) HourlyPrice() float64 {
+	return so.Price
+}
+
+// Type always returns SavingsPlan
+func (so SavingsPlanOffer) Type() OfferType {
+	return SavingsPlan
+}
+
+// Discount returns the fractional saving over the on-demand price
+func (so SavingsPlanOffer) Discount() float64 {
+	if so.Base.HourlyPrice() == 0 {
+		return 0
+	}
+	return 1 - so.Price/so.Base.HourlyPrice()
+}
+
+// String returns a simple string version of the pricing
+func (so SavingsPlanOffer) String() string {
+	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo (%s savings plan %s %s upfront, %0.0f%% off on-demand $%0.3f /hr)",
+		so.HourlyPrice(), so.HourlyPrice()*HoursPerMonth, so.Key.Plan, so.Key.Term, so.Key.Payment, so.Discount()*100, so.Base.HourlyPrice())
+}
+
+// Columns returns a slice of the column names for this type
+func (so SavingsPlanOffer) Columns() []string {
+	return []string{"type", "plan", "term", "upfront", "$/hr", "on-demand $/hr", "discount", "$/mo"}
+}
+
+// RowData returns data for this item for tablular presentation
+// Should be used in concert with Columns
+func (so SavingsPlanOffer) RowData() []string {
+	return []string{so.Name(), so.Key.Plan, so.Key.Term, so.Key.Payment,
+		fmt.Sprintf("$%0.3f", so.Price), fmt.Sprintf("$%0.3f", so.Base.HourlyPrice()),
+		fmt.Sprintf("%0.0f%%", so.Discount()*100), fmt.Sprintf("$%0.2f", so.Price*HoursPerMonth)}
+}
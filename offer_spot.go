@@ -0,0 +1,57 @@
+package awsprice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SpotOffer is an EC2 offer priced from imported spot price history
+type SpotOffer struct {
+	OnDemand EC2Offer
+	Price    float64
+	Stat     string
+	Zones    []string
+	Samples  int
+}
+
+// Name returns the EC2 instance type
+func (so SpotOffer) Name() string {
+	return so.OnDemand.Name()
+}
+
+// HourlyPrice returns the spot price at the chosen statistic
+func (so SpotOffer) HourlyPrice() float64 {
+	return so.Price
+}
+
+// Type always returns Spot
+func (so SpotOffer) Type() OfferType {
+	return Spot
+}
+
+// Discount returns the fractional saving over the on-demand price
+func (so SpotOffer) Discount() float64 {
+	if so.OnDemand.HourlyPrice() == 0 {
+		return 0
+	}
+	return 1 - so.Price/so.OnDemand.HourlyPrice()
+}
+
+// String returns a simple string version of the pricing
+func (so SpotOffer) String() string {
+	return fmt.Sprintf("$%0.3f /hr, $%0.2f /mo (spot %s, %0.0f%% off on-demand $%0.3f /hr)",
+		so.HourlyPrice(), so.HourlyPrice()*HoursPerMonth, so.Stat, so.Discount()*100, so.OnDemand.HourlyPrice())
+}
+
+// Columns returns a slice of the column names for this type
+func (so SpotOffer) Columns() []string {
+	return []string{"type", "AZs", "stat", "samples", "$/hr", "on-demand $/hr", "discount", "$/mo"}
+}
+
+// RowData returns data for this item for tablular presentation
+// Should be used in concert with Columns
+func (so SpotOffer) RowData() []string {
+	return []string{so.Name(), strings.Join(so.Zones, ","), so.Stat, fmt.Sprintf("%d", so.Samples),
+		fmt.Sprintf("$%0.3f", so.Price), fmt.Sprintf("$%0.3f", so.OnDemand.HourlyPrice()),
+		fmt.Sprintf("%0.0f%%", so.Discount()*100), fmt.Sprintf("$%0.2f", so.Price*HoursPerMonth)}
+}
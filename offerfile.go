@@ -0,0 +1,91 @@
+package awsprice
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* Every service's offer file has the same shape: products keyed by SKU,
+ * each with a bag of service-specific attributes, and terms keyed by
+ * SKU. These types are shared by all of them; each service picks the
+ * attributes it cares about into its own <Service>Attr struct.
+ */
+
+// OfferFile is at the root of an offer JSON document
+type OfferFile struct {
+	FormatVersion   string             `json:"formatVersion"`
+	Disclaimer      string             `json:"disclaimer"`
+	OfferCode       string             `json:"offerCode"`
+	PublicationDate string             `json:"publicationDate"`
+	Products        map[string]Product `json:"products"`
+	Terms           Terms              `json:"terms"`
+}
+
+// Product identifies a single product 'leaf' in the offer document.
+// Attribute names are lower-cased, as the CSV and JSON files spell them
+// differently.
+type Product struct {
+	SKU           string            `json:"sku"`
+	ProductFamily string            `json:"productFamily"`
+	Attributes    map[string]string `json:"attributes"`
+}
+
+// Attr returns the named attribute, such as instanceType
+func (p Product) Attr(name string) string {
+	return p.Attributes[strings.ToLower(name)]
+}
+
+// decodeAttr fills a <Service>Attr struct from the product's attributes
+func (p Product) decodeAttr(v interface{}) error {
+	raw, err := json.Marshal(p.Attributes)
+	if err != nil {
+		return err
+	}
+	// encoding/json matches keys to field tags case-insensitively
+	return json.Unmarshal(raw, v)
+}
+
+// normalize lower-cases the attribute names
+func (p *Product) normalize() {
+	attr := make(map[string]string, len(p.Attributes))
+	for name, value := range p.Attributes {
+		attr[strings.ToLower(name)] = value
+	}
+	p.Attributes = attr
+}
+
+// Terms tracks the various terms. For now only OnDemand is used.
+type Terms struct {
+	OnDemand map[string]map[string]TermItem
+}
+
+// TermItem is a given pricing term
+type TermItem struct {
+	OfferTermCode   string                     `json:"offerTermCode"`
+	SKU             string                     `json:"sku"`
+	PriceDimensions map[string]PriceDimensions `json:"priceDimensions"`
+}
+
+// PriceDimensions stores various combinations of billing duration
+// and currency
+type PriceDimensions struct {
+	RateCode     string            `json:"rateCode"`
+	Description  string            `json:"description"`
+	Unit         string            `json:"unit"`
+	PricePerUnit map[string]string `json:"pricePerUnit"`
+}
+
+// simplePrice returns the first USD price found in a SKU's terms
+func simplePrice(terms map[string]TermItem) (float64, error) {
+	for _, term := range terms {
+		for _, dimension := range term.PriceDimensions {
+			price, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
+			if err == nil {
+				return price, nil
+			}
+		}
+	}
+	return 0.0, fmt.Errorf("Error getting pricing from %+v", terms)
+}
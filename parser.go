@@ -1,13 +1,45 @@
 package awsprice
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
 // ParseInput takes a pricer and the input string and returns
 // a string representation of the price
 func ParseInput(pricer Pricer, input string) (string, error) {
 
-	attr := make(map[string]string)
-	offer, err := pricer.Get(input, attr)
+	offerType, conditions, search, err := parseSearch(input)
+	if err != nil {
+		return "", err
+	}
+	if search {
+		offers, err := pricer.Find(offerType, conditions)
+		if err != nil {
+			return "", err
+		}
+		if len(offers) == 0 {
+			return "", &MissingError{What: "offers matching " + strings.TrimSpace(input)}
+		}
+		return PriceTable(offers), nil
+	}
+
+	q, err := parseQuery(input)
 	if err != nil {
-		prices := pricer.Search(input, attr)
+		return "", err
+	}
+	offer, err := pricer.Get(q)
+	var ambiguous *AmbiguousError
+	if errors.As(err, &ambiguous) {
+		return "", err
+	}
+	if err != nil {
+		prices, searchErr := pricer.Search(q)
+		if searchErr != nil {
+			return "", searchErr
+		}
 		if len(prices) == 0 {
 			return "", err
 		}
@@ -19,3 +51,68 @@ func ParseInput(pricer Pricer, input string) (string, error) {
 	}
 	return offer.String(), nil
 }
+
+// splitOffer splits an offer token like 'efs(size=500GB, class=ia)'
+// into its name and the text of its arguments
+func splitOffer(input string) (string, string, error) {
+	input = strings.TrimSpace(input)
+	start := 0
+	if strings.HasPrefix(input, "/") {
+		// a /regex/ name may have parentheses of its own
+		start = len(input)
+		if end := strings.LastIndex(input, "/("); end > 0 {
+			start = end + 1
+		}
+	}
+	open := strings.Index(input[start:], "(")
+	if open == -1 {
+		return input, "", nil
+	}
+	open += start
+	if !strings.HasSuffix(input, ")") {
+		return "", "", fmt.Errorf("Missing closing parenthesis in %s", input)
+	}
+	return strings.TrimSpace(input[:open]), input[open+1 : len(input)-1], nil
+}
+
+// serviceQualifier matches the service an offer name may be qualified
+// with, like the ec2 of 'ec2:m5.large'
+var serviceQualifier = regexp.MustCompile(`^\s*([A-Za-z0-9]+):`)
+
+// parseQuery parses an offer token, optionally qualified with its
+// service, like 'ec2:m5.large(region=eu-west-1)'
+func parseQuery(input string) (Query, error) {
+	var q Query
+	if m := serviceQualifier.FindStringSubmatch(input); m != nil {
+		q.Service = m[1]
+		input = input[len(m[0]):]
+	}
+	name, attr, err := parseOffer(input)
+	if err != nil {
+		return q, err
+	}
+	q.Name, q.Attr = name, attr
+	return q, nil
+}
+
+// parseOffer splits an offer token like 'efs(size=500GB, class=ia)'
+// into its name and attributes
+func parseOffer(input string) (string, map[string]string, error) {
+	name, args, err := splitOffer(input)
+	if err != nil {
+		return "", nil, err
+	}
+	attr := make(map[string]string)
+	for _, arg := range strings.Split(args, ",") {
+		arg = strings.TrimSpace(arg)
+		if arg == "" {
+			continue
+		}
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return "", nil, fmt.Errorf("Argument %s should be key=value", arg)
+		}
+		attr[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+	}
+	return name, attr, nil
+}
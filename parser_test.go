@@ -0,0 +1,68 @@
+package awsprice
+
+import "testing"
+
+func TestParseOffer(t *testing.T) {
+	name, attr, err := parseOffer("efs(size=500GB, class=ia)")
+	if err != nil {
+		t.Fatalf("Error parsing: %v", err)
+	}
+	if name != "efs" || attr["size"] != "500GB" || attr["class"] != "ia" {
+		t.Errorf("Unexpected parse: %s %v", name, attr)
+	}
+	if _, _, err := parseOffer("efs(size=500GB"); err == nil {
+		t.Error("Expected an error for a missing parenthesis")
+	}
+}
+
+func TestParseSearch(t *testing.T) {
+	offerType, conditions, ok, err := parseSearch("ec2(vcpu>=8, mem >= 32GB, family=general, maxprice=0.5)")
+	if err != nil || !ok || offerType != EC2 {
+		t.Fatalf("Expected an EC2 search, got %v %v %v", offerType, ok, err)
+	}
+	expected := []Condition{{"vcpu", ">=", "8"}, {"mem", ">=", "32GB"}, {"family", "=", "general"}, {"maxprice", "=", "0.5"}}
+	if len(conditions) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, conditions)
+	}
+	for i := range expected {
+		if conditions[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], conditions[i])
+		}
+	}
+	if _, _, ok, _ := parseSearch("efs(size=500GB)"); ok {
+		t.Error("Expected efs(...) to be a lookup, not a search")
+	}
+	if _, _, _, err := parseSearch("ec2(vcpu)"); err == nil {
+		t.Error("Expected an error for an argument without a comparison")
+	}
+}
+
+func TestParseOfferPattern(t *testing.T) {
+	name, attr, err := parseOffer(`/^(c|m)5\./(region=eu-west-1)`)
+	if err != nil || name != `/^(c|m)5\./` || attr["region"] != "eu-west-1" {
+		t.Errorf("Unexpected parse: %s %v %v", name, attr, err)
+	}
+	name, attr, err = parseOffer(`/^(c|m)5\./`)
+	if err != nil || name != `/^(c|m)5\./` || len(attr) != 0 {
+		t.Errorf("Unexpected parse: %s %v %v", name, attr, err)
+	}
+	name, _, err = parseOffer("m5*.xlarge(region=us-east-1)")
+	if err != nil || name != "m5*.xlarge" {
+		t.Errorf("Unexpected parse: %s %v", name, err)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	q, err := parseQuery("ec2:m5.large(region=eu-west-1)")
+	if err != nil || q.Service != "ec2" || q.Name != "m5.large" || q.Attr["region"] != "eu-west-1" {
+		t.Errorf("Unexpected parse: %+v %v", q, err)
+	}
+	q, err = parseQuery(`opensearch:/^m5\./`)
+	if err != nil || q.Service != "opensearch" || q.Name != `/^m5\./` {
+		t.Errorf("Unexpected parse: %+v %v", q, err)
+	}
+	q, err = parseQuery("db.t3.medium")
+	if err != nil || q.Service != "" || q.Name != "db.t3.medium" {
+		t.Errorf("Unexpected parse: %+v %v", q, err)
+	}
+}
@@ -5,8 +5,8 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 
@@ -25,8 +25,51 @@ const (
 	RDS
 	S3
 	EBS
+	EFS
+	FSx
+	Fargate
+	EKS
+	OpenSearch
+	Redshift
+	MSK
+	Spot
+	SavingsPlan
 )
 
+var offerTypeNames = map[OfferType]string{
+	EC2:         "EC2",
+	RDS:         "RDS",
+	S3:          "S3",
+	EBS:         "EBS",
+	EFS:         "EFS",
+	FSx:         "FSx",
+	Fargate:     "Fargate",
+	EKS:         "EKS",
+	OpenSearch:  "OpenSearch",
+	Redshift:    "Redshift",
+	MSK:         "MSK",
+	Spot:        "Spot",
+	SavingsPlan: "SavingsPlan",
+}
+
+// String returns the name of the offer type, like EC2
+func (ot OfferType) String() string {
+	if name, ok := offerTypeNames[ot]; ok {
+		return name
+	}
+	return fmt.Sprintf("OfferType(%d)", int(ot))
+}
+
+// offerTypeNamed finds an offer type by its (case insensitive) name
+func offerTypeNamed(name string) (OfferType, bool) {
+	for offerType, typeName := range offerTypeNames {
+		if strings.EqualFold(typeName, name) {
+			return offerType, true
+		}
+	}
+	return 0, false
+}
+
 // OfferList is a slice of Offers
 type OfferList []Offer
 
@@ -35,7 +78,10 @@ func (slice OfferList) Len() int {
 }
 
 func (slice OfferList) Less(i, j int) bool {
-	return slice[i].HourlyPrice() < slice[j].HourlyPrice()
+	if slice[i].HourlyPrice() != slice[j].HourlyPrice() {
+		return slice[i].HourlyPrice() < slice[j].HourlyPrice()
+	}
+	return slice[i].Name() < slice[j].Name()
 }
 
 func (slice OfferList) Swap(i, j int) {
@@ -80,137 +126,346 @@ type Offer interface {
 	// Description() string
 }
 
-// Pricer is a standard interface for price lookups. Given a name and
-// defined attributes, (such as Region), it will return a floating point
-// hourly price
+// OfferKey is what an offer is stored under: a name, like m5.large, and
+// the attributes, like region, that pick between offers of that name.
+// Each offer type builds its own map key from it.
+type OfferKey struct {
+	Name string
+	Attr map[string]string
+}
+
+// Query asks for offers by name, or by a name pattern in Search, with
+// attributes like region, market or size that pick and price them
+type Query struct {
+	// Service qualifies the name with an offer type, like the ec2 of
+	// ec2:m5.large, for names with offers of more than one type
+	Service string
+	Name    string
+	Attr    map[string]string
+}
+
+// Pricer is a standard interface for price lookups. Offers of any type
+// are stored by key and looked up by query, so adding an offer type
+// doesn't change the interface. Close releases anything the Pricer
+// holds open, like the lock on a bolt file.
 type Pricer interface {
-	StoreEC2(name string, attr map[string]string, offer EC2Offer) error
-	StoreRDS(name string, attr map[string]string, offer RDSOffer) error
-	Get(name string, attr map[string]string) (Offer, error)
-	Search(name string, attr map[string]string) []Offer
+	Store(key OfferKey, offer Offer) error
+	Get(q Query) (Offer, error)
+	Search(q Query) ([]Offer, error)
+	Find(offerType OfferType, conditions []Condition) ([]Offer, error)
+	Close() error
+}
+
+// storable is an offer that can be kept in a PriceDB. Each offer type
+// builds its map key from the OfferKey it's stored under.
+type storable interface {
+	Offer
+	storeIn(pd *PriceDB, key OfferKey) error
+}
+
+// offerGetters look up each type of offer in a PriceDB
+var offerGetters = map[OfferType]func(pd *PriceDB, q Query) (Offer, error){
+	EC2:        (*PriceDB).getEC2,
+	RDS:        (*PriceDB).getRDS,
+	EFS:        (*PriceDB).getEFS,
+	FSx:        (*PriceDB).getFSx,
+	Fargate:    (*PriceDB).getFargate,
+	EKS:        (*PriceDB).getEKS,
+	OpenSearch: (*PriceDB).getOpenSearch,
+	Redshift:   (*PriceDB).getRedshift,
+	MSK:        (*PriceDB).getMSK,
 }
 
 // PriceDB is the high level storage container
 // for all the pricing data. It has utility methods for storing,
 // loading, and searching the price data.
 type PriceDB struct {
-	// OfferLookup maps a name (like 'm4.xlarge') to a type (EC2)
-	OfferLookup map[string]OfferType
+	// OfferLookup maps a name (like 'm4.xlarge') to the types with
+	// offers of that name (EC2)
+	OfferLookup map[string][]OfferType
 	EC2         map[EC2OfferParam]EC2Offer
 	RDS         map[RDSOfferParam]RDSOffer
+	EFS         map[EFSOfferParam]EFSOffer
+	FSx         map[FSxOfferParam]FSxOffer
+	Fargate     map[FargateOfferParam]FargateOffer
+	EKS         map[EKSOfferParam]EKSOffer
+	OpenSearch  map[OpenSearchOfferParam]OpenSearchOffer
+	Redshift    map[RedshiftOfferParam]RedshiftOffer
+	MSK         map[MSKOfferParam]MSKOffer
+	// Spot holds imported spot price history
+	Spot map[SpotKey][]SpotObservation
+	// SavingsPlans maps plans and the usage they cover to a discounted rate
+	SavingsPlans map[SavingsPlanKey]float64
+	// Header describes how and from what the DB was built. It's saved
+	// ahead of the offers, so a DB can be checked before it's decoded.
+	Header DBHeader
 }
 
 const summaryDBFile = "_SummaryDB_v0.2.gob"
 
-// StoreEC2 sets a value (with optional attributes) to a given hourly price
-func (pd *PriceDB) StoreEC2(name string, attr map[string]string, offer EC2Offer) error {
+// Store sets an offer of any type under a key
+func (pd *PriceDB) Store(key OfferKey, offer Offer) error {
+	s, ok := offer.(storable)
+	if !ok {
+		return fmt.Errorf("Unable to store %s offers", offer.Type())
+	}
+	return s.storeIn(pd, key)
+}
 
-	// register this name as an EC2 type.
-	pd.OfferLookup[name] = EC2
-	offerParam, err := NewEC2OfferParam(name, attr)
-	if err != nil {
-		return err
+// register records that a name has offers of a type
+func (pd *PriceDB) register(name string, offerType OfferType) {
+	pd.OfferLookup[name] = withType(pd.OfferLookup[name], offerType)
+}
+
+// withType adds a type to a name's types, keeping them in order
+func withType(types []OfferType, offerType OfferType) []OfferType {
+	for _, t := range types {
+		if t == offerType {
+			return types
+		}
 	}
-	(*pd).EC2[offerParam] = offer
-	return nil
+	types = append(types, offerType)
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
 }
 
-// StoreRDS sets a value (with optional attributes) to a given hourly price
-func (pd *PriceDB) StoreRDS(name string, attr map[string]string, offer RDSOffer) error {
+// Lookup returns the types with offers of a name, like [EC2] for
+// m5.large
+func (pd *PriceDB) Lookup(name string) []OfferType {
+	return pd.OfferLookup[name]
+}
 
-	// register this name as an EC2 type.
-	pd.OfferLookup[name] = RDS
-	offerParam, err := NewRDSOfferParam(name, attr)
-	if err != nil {
-		return err
+// queryService returns the offer type a query is qualified with, if any
+func queryService(q Query) (OfferType, bool, error) {
+	if q.Service == "" {
+		return 0, false, nil
 	}
-	(*pd).RDS[offerParam] = offer
-	return nil
+	offerType, ok := offerTypeNamed(q.Service)
+	if !ok {
+		return 0, false, fmt.Errorf("Unknown service %s", q.Service)
+	}
+	return offerType, true, nil
 }
 
-// Get returns an hourly price (or an error, if such a thing happens)
-// when given a name and optional attributes
-func (pd *PriceDB) Get(name string, attr map[string]string) (Offer, error) {
+// Close does nothing, as a PriceDB is held entirely in memory
+func (pd *PriceDB) Close() error {
+	return nil
+}
 
-	offerType, ok := (*pd).OfferLookup[name]
+// Get returns the offer for a query's name and attributes. A name with
+// offers of several types is resolved by the query's Service, or else
+// by which of the types have an offer matching the attributes; if more
+// than one does, the query is ambiguous.
+func (pd *PriceDB) Get(q Query) (Offer, error) {
+	types, ok := pd.OfferLookup[q.Name]
 	if !ok {
-		return nil, fmt.Errorf("No known resources named %s", name)
+		return nil, &MissingError{What: "known resources named " + q.Name}
 	}
-	switch offerType {
-	case EC2:
-		offerParam, err := NewEC2OfferParam(name, attr)
-		if err != nil {
-			return nil, err
+	service, qualified, err := queryService(q)
+	if err != nil {
+		return nil, err
+	}
+	var found Offer
+	var firstErr error
+	matched := make([]OfferType, 0, 1)
+	for _, offerType := range types {
+		if qualified && offerType != service {
+			continue
 		}
-		if ec2Offer, ok := (*pd).EC2[offerParam]; ok {
-			return ec2Offer, nil
+		get, ok := offerGetters[offerType]
+		if !ok {
+			continue
 		}
-		return nil, fmt.Errorf("No matching EC2 records found")
-	case RDS:
-		offerParam, err := NewRDSOfferParam(name, attr)
+		offer, err := get(pd, q)
 		if err != nil {
-			return nil, err
-		}
-		if rdsOffer, ok := (*pd).RDS[offerParam]; ok {
-			return rdsOffer, nil
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
 		}
-		return nil, fmt.Errorf("No matching RDS records found")
+		found = offer
+		matched = append(matched, offerType)
+	}
+	switch {
+	case len(matched) == 1:
+		return found, nil
+	case len(matched) > 1:
+		return nil, &AmbiguousError{Name: q.Name, Types: matched}
+	case firstErr != nil:
+		return nil, firstErr
+	case qualified:
+		return nil, &MissingError{What: fmt.Sprintf("%s resources named %s", service, q.Name)}
 	}
 	return nil, errors.New("Pricing data not found")
 }
 
-// Search returns a slice of all matching Offers
-func (pd *PriceDB) Search(name string, attr map[string]string) []Offer {
-	results := make([]Offer, 0, 6)
-	for key := range (*pd).OfferLookup {
-		if strings.Contains(key, name) {
-			offer, err := pd.Get(key, attr)
+// Search returns the offers whose names match the query's pattern,
+// cheapest first and then by name. See MatchName for the patterns
+// understood. Every type with offers of a name is included, unless the
+// query is qualified with a Service.
+func (pd *PriceDB) Search(q Query) ([]Offer, error) {
+	match, err := MatchName(q.Name)
+	if err != nil {
+		return nil, err
+	}
+	service, qualified, err := queryService(q)
+	if err != nil {
+		return nil, err
+	}
+	results := make(OfferList, 0, 6)
+	for name, types := range pd.OfferLookup {
+		if !match(name) {
+			continue
+		}
+		for _, offerType := range types {
+			if qualified && offerType != service {
+				continue
+			}
+			offer, err := pd.Get(Query{Service: offerType.String(), Name: name, Attr: q.Attr})
 			if err == nil {
 				results = append(results, offer)
 			}
 		}
 	}
-	return results
+	sort.Sort(results)
+	return results, nil
 }
 
-func (pd PriceDB) save() error {
-	file, err := os.Create(filepath.Join(cacheDir, summaryDBFile))
-	defer func() {
-		err = file.Close()
-	}()
-	if err != nil {
-		return err
+// carryLookup copies the names of one offer type from another DB
+func (pd *PriceDB) carryLookup(from *PriceDB, offerType OfferType) {
+	for name, types := range from.OfferLookup {
+		for _, t := range types {
+			if t == offerType {
+				pd.register(name, t)
+			}
+		}
 	}
+}
 
-	encoder := gob.NewEncoder(file)
-	err = encoder.Encode(pd)
-	return err
+// carry copies all the offers of one type, and their names, from
+// another DB
+func (pd *PriceDB) carry(from *PriceDB, offerType OfferType) {
+	switch offerType {
+	case EC2:
+		pd.EC2 = from.EC2
+	case RDS:
+		pd.RDS = from.RDS
+	case EFS:
+		pd.EFS = from.EFS
+	case FSx:
+		pd.FSx = from.FSx
+	case Fargate:
+		pd.Fargate = from.Fargate
+	case EKS:
+		pd.EKS = from.EKS
+	case OpenSearch:
+		pd.OpenSearch = from.OpenSearch
+	case Redshift:
+		pd.Redshift = from.Redshift
+	case MSK:
+		pd.MSK = from.MSK
+	}
+	pd.carryLookup(from, offerType)
+}
+
+// saveFile writes the DB to path, replacing any previous DB only once
+// it's written in full
+func (pd PriceDB) saveFile(path string) error {
+	return replaceFile(path, pd.encode)
+}
+
+// encode writes the DB's header, then the DB, as gobs
+func (pd PriceDB) encode(w io.Writer) error {
+	header := pd.header()
+	pd.Header = DBHeader{}
+	encoder := gob.NewEncoder(w)
+	if err := encoder.Encode(header); err != nil {
+		return err
+	}
+	return encoder.Encode(pd)
 }
 
-// LoadPriceDB loads the simple pricing "database" into memory
-// The current implementation is a GOB file
-func LoadPriceDB() (*PriceDB, error) {
-	file, err := os.Open(filepath.Join(cacheDir, summaryDBFile))
-	defer func() {
-		err = file.Close()
-	}()
+// LoadPriceDBFile loads a pricing "database" saved at path
+func LoadPriceDBFile(path string) (*PriceDB, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, &MissingError{What: "pricing db " + path, Err: err}
+	}
 	if err != nil {
 		return nil, err
 	}
-	decoder := gob.NewDecoder(file)
+	defer file.Close()
+	return decodePriceDB(file, path)
+}
+
+// decodePriceDB reads a DB written by encode, checking its header
+// before the offers are decoded. name is reported in a ParseError.
+func decodePriceDB(r io.Reader, name string) (*PriceDB, error) {
+	decoder := gob.NewDecoder(r)
+	var header DBHeader
+	if err := decoder.Decode(&header); err != nil {
+		return nil, &ParseError{File: name, Err: err}
+	}
+	if err := header.check(); err != nil {
+		return nil, &ParseError{File: name, Err: err}
+	}
 	db := NewPriceDB()
-	err = decoder.Decode(db)
-	if err != nil {
-		return nil, err
+	if err := decoder.Decode(db); err != nil {
+		return nil, &ParseError{File: name, Err: err}
 	}
+	db.Header = header
 	return db, nil
 }
 
+// spotFields decodes just the spot history from a gob PriceDB
+type spotFields struct {
+	Spot map[SpotKey][]SpotObservation
+}
+
+// loadSpotFile reads the spot history from the gob DB at path without
+// checking its header. Files from before the header start with the
+// offers instead.
+func loadSpotFile(path string) (map[SpotKey][]SpotObservation, error) {
+	var err error
+	for _, headed := range []bool{true, false} {
+		var fields spotFields
+		if err = decodeSpotFile(path, headed, &fields); err == nil {
+			return fields.Spot, nil
+		}
+	}
+	return nil, &ParseError{File: path, Err: err}
+}
+
+func decodeSpotFile(path string, headed bool, fields *spotFields) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := gob.NewDecoder(file)
+	if headed {
+		var header DBHeader
+		if err := decoder.Decode(&header); err != nil {
+			return err
+		}
+	}
+	return decoder.Decode(fields)
+}
+
 // NewPriceDB creates a new PriceDB data structure
 func NewPriceDB() *PriceDB {
 	db := PriceDB{}
-	db.OfferLookup = make(map[string]OfferType)
+	db.OfferLookup = make(map[string][]OfferType)
 	db.EC2 = make(map[EC2OfferParam]EC2Offer)
 	db.RDS = make(map[RDSOfferParam]RDSOffer)
+	db.EFS = make(map[EFSOfferParam]EFSOffer)
+	db.FSx = make(map[FSxOfferParam]FSxOffer)
+	db.Fargate = make(map[FargateOfferParam]FargateOffer)
+	db.EKS = make(map[EKSOfferParam]EKSOffer)
+	db.OpenSearch = make(map[OpenSearchOfferParam]OpenSearchOffer)
+	db.Redshift = make(map[RedshiftOfferParam]RedshiftOffer)
+	db.MSK = make(map[MSKOfferParam]MSKOffer)
+	db.Spot = make(map[SpotKey][]SpotObservation)
+	db.SavingsPlans = make(map[SavingsPlanKey]float64)
 	return &db
 }
@@ -0,0 +1,116 @@
+package awsprice
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	db := NewPriceDB()
+	key := OfferKey{Name: "efs", Attr: map[string]string{"region": "us-west-2", "class": "ia"}}
+	if err := db.Store(key, EFSOffer{Price: 0.025}); err != nil {
+		t.Fatalf("Error storing EFS offer: %v", err)
+	}
+	offer, err := db.Get(Query{Name: "efs", Attr: map[string]string{"class": "ia", "size": "100GB"}})
+	if err != nil {
+		t.Fatalf("Error getting EFS offer: %v", err)
+	}
+	if offer.Type() != EFS || offer.(EFSOffer).MonthlyPrice() != 2.5 {
+		t.Errorf("Expected $2.50 /mo for 100GB, got %v", offer)
+	}
+
+	// offers derived at lookup time aren't stored themselves
+	if err := db.Store(OfferKey{Name: "m5.large"}, SpotOffer{}); err == nil {
+		t.Error("Expected an error storing a spot offer")
+	}
+	if err := db.Store(OfferKey{Name: "m5.large", Attr: map[string]string{"region": "nowhere"}}, EC2Offer{}); err == nil {
+		t.Error("Expected an error for an unknown region")
+	}
+	if _, ok := db.OfferLookup["m5.large"]; ok {
+		t.Error("Expected m5.large not to be registered after failing to store it")
+	}
+}
+
+// ambiguousDB has m5.large as both an EC2 and an OpenSearch instance,
+// with the OpenSearch one only in eu-west-1
+func ambiguousDB(t *testing.T) *PriceDB {
+	db := NewPriceDB()
+	for _, store := range []struct {
+		region string
+		offer  Offer
+	}{
+		{"us-west-2", EC2Offer{Price: 0.096}},
+		{"eu-west-1", EC2Offer{Price: 0.107}},
+		{"eu-west-1", OpenSearchOffer{Price: 0.158}},
+	} {
+		key := OfferKey{Name: "m5.large", Attr: map[string]string{"region": store.region}}
+		if err := db.Store(key, store.offer); err != nil {
+			t.Fatalf("Error storing %s offer: %v", store.offer.Type(), err)
+		}
+	}
+	return db
+}
+
+func TestAmbiguousName(t *testing.T) {
+	db := ambiguousDB(t)
+	if types := db.Lookup("m5.large"); len(types) != 2 || types[0] != EC2 || types[1] != OpenSearch {
+		t.Errorf("Expected EC2 and OpenSearch for m5.large, got %v", types)
+	}
+
+	dir, err := ioutil.TempDir("", "awsprice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, boltDBFile)
+	if err := (BoltBackend{}).Save(path, db); err != nil {
+		t.Fatal(err)
+	}
+	bp, err := OpenBoltPricer(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bp.Close()
+
+	for _, pricer := range []Pricer{db, bp} {
+		eu := map[string]string{"region": "eu-west-1"}
+		var ambiguous *AmbiguousError
+		if _, err := pricer.Get(Query{Name: "m5.large", Attr: eu}); !errors.As(err, &ambiguous) {
+			t.Errorf("%T: expected an AmbiguousError in eu-west-1, got %v", pricer, err)
+		}
+		// only EC2 has an offer in the default region
+		offer, err := pricer.Get(Query{Name: "m5.large"})
+		if err != nil || offer.Type() != EC2 {
+			t.Errorf("%T: expected the EC2 offer in us-west-2, got %v (%v)", pricer, offer, err)
+		}
+		offer, err = pricer.Get(Query{Service: "opensearch", Name: "m5.large", Attr: eu})
+		if err != nil || offer.HourlyPrice() != 0.158 {
+			t.Errorf("%T: expected 0.158 for opensearch:m5.large, got %v (%v)", pricer, offer, err)
+		}
+		var missing *MissingError
+		if _, err := pricer.Get(Query{Service: "rds", Name: "m5.large"}); !errors.As(err, &missing) {
+			t.Errorf("%T: expected a MissingError for rds:m5.large, got %v", pricer, err)
+		}
+		if _, err := pricer.Get(Query{Service: "emr", Name: "m5.large"}); err == nil {
+			t.Errorf("%T: expected an error for an unknown service", pricer)
+		}
+		offers, err := pricer.Search(Query{Name: "m5", Attr: eu})
+		if err != nil || len(offers) != 2 {
+			t.Errorf("%T: expected both m5.large offers from Search, got %v (%v)", pricer, offers, err)
+		}
+
+		if _, err := ParseInput(pricer, "m5.large(region=eu-west-1)"); !errors.As(err, &ambiguous) {
+			t.Errorf("%T: expected ParseInput to report the ambiguity, got %v", pricer, err)
+		} else if !strings.Contains(err.Error(), "ec2:m5.large") {
+			t.Errorf("Expected the qualified names to be suggested, got %v", err)
+		}
+		value, err := ParseInput(pricer, "ec2:m5.large(region=eu-west-1)")
+		if err != nil || !strings.Contains(value, "0.107") {
+			t.Errorf("%T: expected the EC2 price for ec2:m5.large, got %q (%v)", pricer, value, err)
+		}
+	}
+}
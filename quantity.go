@@ -0,0 +1,152 @@
+package awsprice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Quantity is an amount with an optional unit, as given in an offer
+// argument like size=500GB or throughput=128MBps. Rates follow the
+// usual convention that a capital B is bytes and a small b bits, so
+// throughput takes MBps or GB/s and network bandwidth Gbps or Gbit.
+type Quantity struct {
+	Value float64
+	Unit  string
+}
+
+// storageUnits maps storage units to their size in GB. AWS bills
+// "GB-Mo" in binary gigabytes, so GB and GiB are treated the same.
+var storageUnits = map[string]float64{
+	"":    1,
+	"mb":  1.0 / 1024,
+	"mib": 1.0 / 1024,
+	"gb":  1,
+	"gib": 1,
+	"tb":  1024,
+	"tib": 1024,
+	"pb":  1024 * 1024,
+	"pib": 1024 * 1024,
+}
+
+// throughputUnits maps throughput units, in bytes, to their rate in
+// MB/s. See rateUnit for the spellings.
+var throughputUnits = map[string]float64{
+	"":      1,
+	"mb/s":  1,
+	"mib/s": 1,
+	"gb/s":  1024,
+	"gib/s": 1024,
+}
+
+// networkUnits maps network bandwidth units, in bits, to Gbit/s. See
+// rateUnit for the spellings.
+var networkUnits = map[string]float64{
+	"":        1,
+	"gigabit": 1,
+	"gbit":    1,
+	"megabit": 1.0 / 1000,
+	"mbit":    1.0 / 1000,
+}
+
+// rateUnit lower-cases a unit for the tables above, keeping the case of
+// the b in a "ps" rate: MBps becomes mb/s (bytes) while Mbps or mbps
+// becomes mbit (bits)
+func rateUnit(unit string) string {
+	switch {
+	case strings.HasSuffix(unit, "Bps"):
+		return strings.ToLower(strings.TrimSuffix(unit, "Bps")) + "b/s"
+	case strings.HasSuffix(strings.ToLower(unit), "bps"):
+		return strings.ToLower(unit[:len(unit)-len("bps")]) + "bit"
+	}
+	return strings.ToLower(unit)
+}
+
+// ParseQuantity splits a string such as "1.5TB" into its value and unit
+func ParseQuantity(given string) (Quantity, error) {
+	given = strings.TrimSpace(given)
+	split := strings.IndexFunc(given, func(r rune) bool {
+		return !(r >= '0' && r <= '9') && r != '.'
+	})
+	if split == -1 {
+		split = len(given)
+	}
+	value, err := strconv.ParseFloat(given[:split], 64)
+	if err != nil {
+		return Quantity{}, fmt.Errorf("Invalid quantity %q", given)
+	}
+	unit := strings.TrimSpace(given[split:])
+	return Quantity{Value: value, Unit: unit}, nil
+}
+
+// GB returns the quantity as a storage size in GB
+func (q Quantity) GB() (float64, error) {
+	factor, ok := storageUnits[strings.ToLower(q.Unit)]
+	if !ok {
+		return 0, fmt.Errorf("Unknown storage unit %q", q.Unit)
+	}
+	return q.Value * factor, nil
+}
+
+// MBps returns the quantity as a throughput in MB/s
+func (q Quantity) MBps() (float64, error) {
+	factor, ok := throughputUnits[rateUnit(q.Unit)]
+	if !ok {
+		return 0, fmt.Errorf("Unknown throughput unit %q, expected MBps, MB/s or GB/s", q.Unit)
+	}
+	return q.Value * factor, nil
+}
+
+// Gbps returns the quantity as a network bandwidth in Gbit/s
+func (q Quantity) Gbps() (float64, error) {
+	factor, ok := networkUnits[rateUnit(q.Unit)]
+	if !ok {
+		return 0, fmt.Errorf("Unknown network unit %q, expected Gbps, Gbit or Mbps", q.Unit)
+	}
+	return q.Value * factor, nil
+}
+
+// attrGB parses the named attribute as a storage size, falling back
+// to def if it isn't present
+func attrGB(attr map[string]string, key string, def float64) (float64, error) {
+	given, ok := attr[key]
+	if !ok {
+		return def, nil
+	}
+	q, err := ParseQuantity(given)
+	if err != nil {
+		return 0, err
+	}
+	return q.GB()
+}
+
+// attrMBps parses the named attribute as a throughput, falling back
+// to def if it isn't present
+func attrMBps(attr map[string]string, key string, def float64) (float64, error) {
+	given, ok := attr[key]
+	if !ok {
+		return def, nil
+	}
+	q, err := ParseQuantity(given)
+	if err != nil {
+		return 0, err
+	}
+	return q.MBps()
+}
+
+// attrNumber parses the named attribute as a plain count, falling back
+// to def if it isn't present
+func attrNumber(attr map[string]string, key string, def float64) (float64, error) {
+	given, ok := attr[key]
+	if !ok {
+		return def, nil
+	}
+	q, err := ParseQuantity(given)
+	if err != nil {
+		return 0, err
+	}
+	if q.Unit != "" {
+		return 0, fmt.Errorf("Expected a plain number for %s, got %q", key, given)
+	}
+	return q.Value, nil
+}
@@ -0,0 +1,71 @@
+package awsprice
+
+import "testing"
+
+func TestQuantityGB(t *testing.T) {
+	cases := map[string]float64{
+		"500GB":  500,
+		"1.5TB":  1536,
+		"10 GiB": 10,
+		"512MB":  0.5,
+		"20":     20,
+	}
+	for given, expected := range cases {
+		q, err := ParseQuantity(given)
+		if err != nil {
+			t.Errorf("Error parsing %s: %v", given, err)
+			continue
+		}
+		got, err := q.GB()
+		if err != nil {
+			t.Errorf("Error converting %s: %v", given, err)
+		}
+		if got != expected {
+			t.Errorf("Expected %s to be %v GB, got %v", given, expected, got)
+		}
+	}
+}
+
+func TestQuantityMBps(t *testing.T) {
+	q, err := ParseQuantity("128MBps")
+	if err != nil {
+		t.Fatalf("Error parsing: %v", err)
+	}
+	got, err := q.MBps()
+	if err != nil || got != 128 {
+		t.Errorf("Expected 128 MB/s, got %v (%v)", got, err)
+	}
+	if _, err := q.GB(); err == nil {
+		t.Error("Expected an error converting MBps to GB")
+	}
+}
+
+func TestQuantityRates(t *testing.T) {
+	throughput := map[string]float64{"128MBps": 128, "128 MB/s": 128, "64MiBps": 64, "1GBps": 1024, "2GB/s": 2048, "32": 32}
+	for given, expected := range throughput {
+		q, _ := ParseQuantity(given)
+		if got, err := q.MBps(); err != nil || got != expected {
+			t.Errorf("Expected %s to be %v MB/s, got %v (%v)", given, expected, got, err)
+		}
+	}
+	network := map[string]float64{"25Gbps": 25, "25gbps": 25, "10 Gigabit": 10, "100Gbit": 100, "500Mbps": 0.5, "12.5": 12.5}
+	for given, expected := range network {
+		q, _ := ParseQuantity(given)
+		if got, err := q.Gbps(); err != nil || got != expected {
+			t.Errorf("Expected %s to be %v Gbit/s, got %v (%v)", given, expected, got, err)
+		}
+	}
+	// bits aren't a throughput, nor bytes a network speed
+	for _, given := range []string{"1Gbps", "1gbps", "128mbps"} {
+		q, _ := ParseQuantity(given)
+		if _, err := q.MBps(); err == nil {
+			t.Errorf("Expected an error for %s as a throughput", given)
+		}
+	}
+	for _, given := range []string{"1GBps", "1GB/s"} {
+		q, _ := ParseQuantity(given)
+		if _, err := q.Gbps(); err == nil {
+			t.Errorf("Expected an error for %s as a network speed", given)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package awsprice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SavingsPlanRegionIndex lists the per-region rate files of a savings
+// plan offer, as linked from currentSavingsPlanIndexUrl
+type SavingsPlanRegionIndex struct {
+	Disclaimer      string                   `json:"disclaimer"`
+	PublicationDate string                   `json:"publicationDate"`
+	Regions         []SavingsPlanRegionEntry `json:"regions"`
+}
+
+// SavingsPlanRegionEntry identifies the rate file for one region
+type SavingsPlanRegionEntry struct {
+	RegionCode string `json:"regionCode"`
+	VersionURL string `json:"versionUrl"`
+}
+
+// SavingsPlanOfferIndex is at the root of a savings plan rate file
+type SavingsPlanOfferIndex struct {
+	Version         string               `json:"version"`
+	PublicationDate string               `json:"publicationDate"`
+	RegionCode      string               `json:"regionCode"`
+	Products        []SavingsPlanProduct `json:"products"`
+	Terms           SavingsPlanTerms     `json:"terms"`
+}
+
+// SavingsPlanProduct identifies a single plan (type, term and payment option)
+type SavingsPlanProduct struct {
+	SKU           string          `json:"sku"`
+	ProductFamily string          `json:"productFamily"`
+	UsageType     string          `json:"usageType"`
+	Attr          SavingsPlanAttr `json:"attributes"`
+}
+
+// SavingsPlanAttr identifies a selected list of useful attributes
+type SavingsPlanAttr struct {
+	PurchaseOption string `json:"purchaseOption"`
+	PurchaseTerm   string `json:"purchaseTerm"`
+	InstanceType   string `json:"instanceType"`
+	Location       string `json:"location"`
+}
+
+// SavingsPlanTerms holds the rates for each plan
+type SavingsPlanTerms struct {
+	SavingsPlan []SavingsPlanTerm `json:"savingsPlan"`
+}
+
+// SavingsPlanTerm is the set of discounted rates for one plan SKU
+type SavingsPlanTerm struct {
+	SKU   string            `json:"sku"`
+	Rates []SavingsPlanRate `json:"rates"`
+}
+
+// SavingsPlanRate is the discounted price of one usage type
+type SavingsPlanRate struct {
+	DiscountedUsageType   string `json:"discountedUsageType"`
+	DiscountedOperation   string `json:"discountedOperation"`
+	DiscountedServiceCode string `json:"discountedServiceCode"`
+	Unit                  string `json:"unit"`
+	DiscountedRate        struct {
+		Price    string `json:"price"`
+		Currency string `json:"currency"`
+	} `json:"discountedRate"`
+}
+
+// SavingsPlanKey identifies the discounted rate a plan gives one usage
+type SavingsPlanKey struct {
+	Plan    string // compute or ec2
+	Term    string // 1yr or 3yr
+	Payment string // no, partial or all (upfront)
+	Region  Region
+	Service string // ec2 or fargate
+	// Usage is the instance type for ec2 and arch/os/rate for fargate
+	Usage string
+}
+
+var savingsPlanFamilies = map[string]string{
+	"ComputeSavingsPlans":     "compute",
+	"EC2InstanceSavingsPlans": "ec2",
+}
+
+var savingsPlanPayments = map[string]string{
+	"No Upfront":      "no",
+	"Partial Upfront": "partial",
+	"All Upfront":     "all",
+}
+
+// savingsPlanUsage maps a discounted rate to the service and usage
+// it applies to. ok is false for usage we don't model, including
+// Lambda, which has no offers to price.
+func savingsPlanUsage(rate SavingsPlanRate) (service string, usage string, ok bool) {
+	usageType := rate.DiscountedUsageType
+	switch rate.DiscountedServiceCode {
+	case "AmazonEC2":
+		// Linux, shared tenancy only, like extractEC2
+		parts := strings.SplitN(usageType, ":", 2)
+		if len(parts) != 2 || !strings.HasSuffix(parts[0], "BoxUsage") || rate.DiscountedOperation != "RunInstances" {
+			return "", "", false
+		}
+		return "ec2", parts[1], true
+	case "AmazonECS":
+		arch, os, kind, ok := fargateUsage(usageType)
+		if !ok {
+			return "", "", false
+		}
+		return "fargate", arch + "/" + os + "/" + kind, true
+	}
+	return "", "", false
+}
+
+// newSavingsPlanKey builds the plan part of a key from the sp, term and
+// payment attributes
+func newSavingsPlanKey(attr map[string]string, region Region, service string, usage string) (SavingsPlanKey, error) {
+	key := SavingsPlanKey{Term: "1yr", Payment: "no", Region: region, Service: service, Usage: usage}
+	switch strings.ToLower(attr["sp"]) {
+	case "compute":
+		key.Plan = "compute"
+	case "ec2", "ec2instance", "instance":
+		key.Plan = "ec2"
+	default:
+		return key, fmt.Errorf("Unknown savings plan %s", attr["sp"])
+	}
+	if term, ok := attr["term"]; ok {
+		switch strings.ToLower(term) {
+		case "1", "1yr", "1y":
+			key.Term = "1yr"
+		case "3", "3yr", "3y":
+			key.Term = "3yr"
+		default:
+			return key, fmt.Errorf("Unknown savings plan term %s", term)
+		}
+	}
+	if payment, ok := attr["payment"]; ok {
+		key.Payment = strings.TrimSuffix(strings.ToLower(payment), "upfront")
+		if key.Payment != "no" && key.Payment != "partial" && key.Payment != "all" {
+			return key, fmt.Errorf("Unknown savings plan payment %s", payment)
+		}
+	}
+	return key, nil
+}
+
+// ec2SavingsPlan prices an EC2 offer at its savings plan rate
+func (pd *PriceDB) ec2SavingsPlan(onDemand EC2Offer, param EC2OfferParam, attr map[string]string) (Offer, error) {
+	key, err := newSavingsPlanKey(attr, param.Region, "ec2", param.Name)
+	if err != nil {
+		return nil, err
+	}
+	rate, ok := pd.SavingsPlans[key]
+	if !ok {
+		return nil, fmt.Errorf("No %s savings plan rate for %s in %s", key.Plan, param.Name, param.Region)
+	}
+	return SavingsPlanOffer{Base: onDemand, Price: rate, Key: key}, nil
+}
+
+// fargateSavingsPlan prices a sized Fargate offer at its savings plan rates
+func (pd *PriceDB) fargateSavingsPlan(onDemand FargateOffer, param FargateOfferParam, attr map[string]string) (Offer, error) {
+	discounted := onDemand
+	for _, kind := range []string{"vcpu", "gb"} {
+		key, err := newSavingsPlanKey(attr, param.Region, "fargate", param.Arch+"/"+param.OS+"/"+kind)
+		if err != nil {
+			return nil, err
+		}
+		rate, ok := pd.SavingsPlans[key]
+		if !ok {
+			return nil, fmt.Errorf("No %s savings plan rate for Fargate %s in %s", key.Plan, key.Usage, param.Region)
+		}
+		if kind == "vcpu" {
+			discounted.VCPUPrice = rate
+		} else {
+			discounted.GBPrice = rate
+		}
+	}
+	key, err := newSavingsPlanKey(attr, param.Region, "fargate", "")
+	if err != nil {
+		return nil, err
+	}
+	return SavingsPlanOffer{Base: onDemand, Price: discounted.HourlyPrice(), Key: key}, nil
+}
+
+// RecommendCommitment returns the hourly savings plan commitment that
+// exactly covers the given expression, e.g.
+// 'm6i.xlarge(sp=compute, term=3yr)'. The plan defaults to compute.
+func RecommendCommitment(pricer Pricer, input string) (string, error) {
+	q, err := parseQuery(input)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := q.Attr["sp"]; !ok {
+		q.Attr["sp"] = "compute"
+	}
+	offer, err := pricer.Get(q)
+	if err != nil {
+		return "", err
+	}
+	sp, ok := offer.(SavingsPlanOffer)
+	if !ok {
+		return "", fmt.Errorf("%s is not covered by savings plans", q.Name)
+	}
+	return fmt.Sprintf("Commit $%0.3f /hr (%s savings plan, %s, %s upfront) to cover %s: on-demand $%0.3f /hr, %0.0f%% saving",
+		sp.HourlyPrice(), sp.Key.Plan, sp.Key.Term, sp.Key.Payment, input, sp.Base.HourlyPrice(), sp.Discount()*100), nil
+}
@@ -0,0 +1,58 @@
+package awsprice
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSavingsPlanUsage(t *testing.T) {
+	rate := SavingsPlanRate{DiscountedUsageType: "USE1-BoxUsage:m6i.xlarge",
+		DiscountedOperation: "RunInstances", DiscountedServiceCode: "AmazonEC2"}
+	service, usage, ok := savingsPlanUsage(rate)
+	if !ok || service != "ec2" || usage != "m6i.xlarge" {
+		t.Errorf("Unexpected EC2 usage: %s %s %v", service, usage, ok)
+	}
+	rate.DiscountedOperation = "RunInstances:0002"
+	if _, _, ok := savingsPlanUsage(rate); ok {
+		t.Error("Expected Windows usage to be skipped")
+	}
+	rate = SavingsPlanRate{DiscountedUsageType: "USE1-Fargate-ARM-GB-Hours", DiscountedServiceCode: "AmazonECS"}
+	service, usage, ok = savingsPlanUsage(rate)
+	if !ok || service != "fargate" || usage != "arm64/Linux/gb" {
+		t.Errorf("Unexpected Fargate usage: %s %s %v", service, usage, ok)
+	}
+	rate = SavingsPlanRate{DiscountedUsageType: "USE1-Lambda-GB-Second", DiscountedServiceCode: "AWSLambda"}
+	if _, _, ok := savingsPlanUsage(rate); ok {
+		t.Error("Expected Lambda usage to be skipped")
+	}
+}
+
+func TestSavingsPlanOffer(t *testing.T) {
+	db := NewPriceDB()
+	err := db.Store(OfferKey{Name: "m6i.xlarge", Attr: map[string]string{"region": "us-west-2"}}, EC2Offer{Price: 0.192})
+	if err != nil {
+		t.Fatalf("Error storing EC2 offer: %v", err)
+	}
+	region, _ := NewRegion("us-west-2")
+	db.SavingsPlans[SavingsPlanKey{Plan: "compute", Term: "3yr", Payment: "no", Region: region,
+		Service: "ec2", Usage: "m6i.xlarge"}] = 0.096
+
+	offer, err := db.Get(Query{Name: "m6i.xlarge", Attr: map[string]string{"sp": "compute", "term": "3yr", "payment": "no"}})
+	if err != nil {
+		t.Fatalf("Error getting savings plan offer: %v", err)
+	}
+	if offer.HourlyPrice() != 0.096 {
+		t.Errorf("Expected 0.096, got %v", offer.HourlyPrice())
+	}
+	if _, err := db.Get(Query{Name: "m6i.xlarge", Attr: map[string]string{"sp": "compute"}}); err == nil {
+		t.Error("Expected an error for a missing 1yr rate")
+	}
+
+	recommendation, err := RecommendCommitment(db, "m6i.xlarge(term=3yr)")
+	if err != nil {
+		t.Fatalf("Error recommending commitment: %v", err)
+	}
+	if !strings.HasPrefix(recommendation, "Commit $0.096 /hr") {
+		t.Errorf("Unexpected recommendation: %s", recommendation)
+	}
+}
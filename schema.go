@@ -0,0 +1,50 @@
+package awsprice
+
+import "time"
+
+// SchemaVersion is the layout of the summary DB. Bump it whenever a
+// change to PriceDB, or to the offers and params it holds, means a DB
+// saved before the change would decode wrongly or not at all.
+const SchemaVersion = 2
+
+// DBHeader is saved ahead of the offers in a summary DB
+type DBHeader struct {
+	// Schema is the SchemaVersion the DB was saved with
+	Schema int
+	// Published maps each offer code to the latest publication date of
+	// the offer files it was extracted from
+	Published map[string]string
+	// Built is when the offers were extracted
+	Built time.Time
+	// Embedded is set on the snapshot compiled into the binary, by
+	// EmbeddedSnapshot
+	Embedded bool
+}
+
+// header returns the DB's header as it's saved, at SchemaVersion
+func (pd *PriceDB) header() DBHeader {
+	header := pd.Header
+	header.Schema = SchemaVersion
+	return header
+}
+
+// check reports a SchemaError if the header is from another
+// SchemaVersion
+func (h DBHeader) check() error {
+	if h.Schema != SchemaVersion {
+		return &SchemaError{Have: h.Schema, Want: SchemaVersion}
+	}
+	return nil
+}
+
+// PublicationDate returns the newest publication date of the offers the
+// DB was built from, or "" if none are known
+func (h DBHeader) PublicationDate() string {
+	latest := ""
+	for _, date := range h.Published {
+		if date > latest {
+			latest = date
+		}
+	}
+	return latest
+}
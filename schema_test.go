@@ -0,0 +1,132 @@
+package awsprice
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// saveOldSchema writes a gob DB as a different SchemaVersion would
+func saveOldSchema(t *testing.T, path string, db *PriceDB) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	encoder := gob.NewEncoder(file)
+	if err := encoder.Encode(DBHeader{Schema: SchemaVersion + 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := encoder.Encode(db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchemaMismatch(t *testing.T) {
+	client, cleanup := testClient(t, fixtureDir(t))
+	defer cleanup()
+	if err := client.makeCacheDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	// with no offers cached there's nothing to rebuild from
+	saveOldSchema(t, client.path(summaryDBFile), searchDB(t))
+	_, err := client.LoadPriceDB()
+	var schema *SchemaError
+	if !errors.As(err, &schema) || schema.Have != SchemaVersion+1 {
+		t.Fatalf("Expected a SchemaError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "awsprice process --all") {
+		t.Errorf("Expected a suggestion to reprocess, got %v", err)
+	}
+
+	if err := client.FetchJSON(context.Background()); err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+	saveOldSchema(t, client.path(summaryDBFile), searchDB(t))
+	db, err := client.LoadPriceDB()
+	if err != nil {
+		t.Fatalf("Expected the DB to be rebuilt, got %v", err)
+	}
+	if db.Header.Schema != SchemaVersion || db.Header.Published["AmazonEC2"] != "2023-01-01T00:00:00Z" {
+		t.Errorf("Unexpected header %+v", db.Header)
+	}
+	if _, err := db.Get(Query{Name: "m4.xlarge"}); err != nil {
+		t.Errorf("Expected m4.xlarge in the rebuilt DB: %v", err)
+	}
+}
+
+func TestBoltSchemaMismatch(t *testing.T) {
+	client, cleanup := testClient(t, fixtureDir(t))
+	defer cleanup()
+	client.Backend = BoltBackend{}
+	if err := client.makeCacheDir(); err != nil {
+		t.Fatal(err)
+	}
+	// a bolt file from before the header existed
+	pricer, err := OpenBoltPricer(client.path(boltDBFile), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pricer.db.Update(func(tx *bolt.Tx) error { return tx.DeleteBucket(metaBucket) }); err != nil {
+		t.Fatal(err)
+	}
+	pricer.Close()
+
+	_, err = client.OpenPricer()
+	var schema *SchemaError
+	if !errors.As(err, &schema) || schema.Have != 0 {
+		t.Errorf("Expected a SchemaError, got %v", err)
+	}
+}
+
+func TestSchemaMismatchKeepsSpot(t *testing.T) {
+	for _, backend := range []Backend{GobBackend{}, BoltBackend{}} {
+		client, cleanup := testClient(t, fixtureDir(t))
+		client.Backend = backend
+		if err := client.FetchJSON(context.Background()); err != nil {
+			t.Fatalf("Error fetching: %v", err)
+		}
+		old := NewPriceDB()
+		if _, err := old.AddSpotHistory(strings.NewReader(spotHistory)); err != nil {
+			t.Fatal(err)
+		}
+		path := client.path(backend.File())
+		if _, ok := backend.(GobBackend); ok {
+			saveOldSchema(t, path, old)
+		} else {
+			if err := backend.Save(path, old); err != nil {
+				t.Fatal(err)
+			}
+			db, err := bolt.Open(path, 0644, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = db.Update(func(tx *bolt.Tx) error {
+				return writeHeader(tx, DBHeader{Schema: SchemaVersion + 1})
+			})
+			db.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		db, err := client.LoadPriceDB()
+		if err != nil {
+			t.Fatalf("%T: expected the DB to be rebuilt, got %v", backend, err)
+		}
+		if !reflect.DeepEqual(db.Spot, old.Spot) {
+			t.Errorf("%T: expected the spot history to be kept by the rebuild, got %v", backend, db.Spot)
+		}
+		if _, err := db.Get(Query{Name: "m4.xlarge"}); err != nil {
+			t.Errorf("%T: expected m4.xlarge in the rebuilt DB: %v", backend, err)
+		}
+		cleanup()
+	}
+}
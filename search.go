@@ -0,0 +1,292 @@
+package awsprice
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MatchName returns a test of offer names for a Search pattern, which
+// is one of
+//
+//   - a glob, if it has any of * ? or [, like m5*.xlarge or *.metal
+//   - a regular expression between slashes, like /^c[67]g\./
+//   - otherwise a substring, so m5 matches m5a.large and m5zn.large
+func MatchName(pattern string) (func(name string) bool, error) {
+	switch {
+	case len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/"):
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid pattern %s: %v", pattern, err)
+		}
+		return re.MatchString, nil
+	case strings.ContainsAny(pattern, "*?["):
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid pattern %s: %v", pattern, err)
+		}
+		return func(name string) bool {
+			matched, _ := path.Match(pattern, name)
+			return matched
+		}, nil
+	}
+	return func(name string) bool { return strings.Contains(name, pattern) }, nil
+}
+
+// Condition is one comparison in an attribute search, like vcpu>=8
+type Condition struct {
+	Attr  string
+	Op    string
+	Value string
+}
+
+// conditionOps are the comparisons understood, longest first so that
+// >= isn't read as >
+var conditionOps = []string{">=", "<=", "!=", "=", ">", "<"}
+
+// searchTypes are the offer types that can be searched by attribute,
+// by the name a search starts with
+var searchTypes = map[string]OfferType{
+	"ec2": EC2,
+}
+
+// parseSearch reads an attribute search like
+// 'ec2(vcpu>=8, mem>=32GB, family=general, maxprice=0.5)'. It reports
+// false if the input isn't a search, but a named offer to look up.
+func parseSearch(input string) (OfferType, []Condition, bool, error) {
+	name, args, err := splitOffer(input)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	offerType, ok := searchTypes[strings.ToLower(name)]
+	if !ok {
+		return 0, nil, false, nil
+	}
+	conditions := make([]Condition, 0)
+	for _, arg := range strings.Split(args, ",") {
+		arg = strings.TrimSpace(arg)
+		if arg == "" {
+			continue
+		}
+		condition, err := parseCondition(arg)
+		if err != nil {
+			return 0, nil, true, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return offerType, conditions, true, nil
+}
+
+// parseCondition splits an argument like mem>=32GB at its comparison
+func parseCondition(arg string) (Condition, error) {
+	at, op := -1, ""
+	for _, candidate := range conditionOps {
+		if i := strings.Index(arg, candidate); i != -1 && (at == -1 || i < at) {
+			at, op = i, candidate
+		}
+	}
+	if at <= 0 {
+		return Condition{}, fmt.Errorf("Argument %s should be a comparison like vcpu>=8", arg)
+	}
+	return Condition{
+		Attr:  strings.ToLower(strings.TrimSpace(arg[:at])),
+		Op:    op,
+		Value: strings.TrimSpace(arg[at+len(op):]),
+	}, nil
+}
+
+// compare applies the condition's comparison to a number
+func (c Condition) compare(have, want float64) bool {
+	switch c.Op {
+	case ">=":
+		return have >= want
+	case "<=":
+		return have <= want
+	case ">":
+		return have > want
+	case "<":
+		return have < want
+	case "!=":
+		return have != want
+	}
+	return have == want
+}
+
+// equality checks the condition is = or !=, for attributes that can't
+// be ordered
+func (c Condition) equality() error {
+	if c.Op != "=" && c.Op != "!=" {
+		return fmt.Errorf("%s can only be compared with = or !=", c.Attr)
+	}
+	return nil
+}
+
+// number parses the condition's value as a plain number
+func (c Condition) number() (float64, error) {
+	return attrNumber(map[string]string{c.Attr: c.Value}, c.Attr, 0)
+}
+
+// searchRegion returns the region a search is limited to, which is
+// the default region unless a region=... condition says otherwise
+func searchRegion(conditions []Condition) (Region, error) {
+	for _, c := range conditions {
+		if c.Attr != "region" {
+			continue
+		}
+		if c.Op != "=" {
+			return "", fmt.Errorf("region can only be compared with =")
+		}
+		return NewRegion(c.Value)
+	}
+	return defaultRegion, nil
+}
+
+// quantity parses the condition's value with a unit, converted by one
+// of the Quantity methods such as Quantity.GB
+func (c Condition) quantity(convert func(q Quantity) (float64, error)) (float64, error) {
+	q, err := ParseQuantity(c.Value)
+	if err != nil {
+		return 0, err
+	}
+	return convert(q)
+}
+
+// contains tests for the condition's value within s, ignoring case
+func (c Condition) contains(s string) bool {
+	match := strings.Contains(strings.ToLower(s), strings.ToLower(c.Value))
+	return match == (c.Op == "=")
+}
+
+// ec2Numbers are the numeric EC2 specs that can be searched, and how
+// the value being compared to is read
+var ec2Numbers = map[string]struct {
+	spec  func(eo EC2Offer) float64
+	parse func(q Quantity) (float64, error)
+}{
+	"vcpu":    {func(eo EC2Offer) float64 { return eo.VCPUs }, plainNumber},
+	"mem":     {func(eo EC2Offer) float64 { return eo.MemoryGB }, Quantity.GB},
+	"clock":   {func(eo EC2Offer) float64 { return eo.ClockGHz }, plainNumber},
+	"network": {func(eo EC2Offer) float64 { return eo.NetworkGbps }, Quantity.Gbps},
+	"ebs":     {func(eo EC2Offer) float64 { return eo.EBSMbps }, plainNumber},
+	"gpu":     {func(eo EC2Offer) float64 { return eo.GPUs }, plainNumber},
+	"gpumem":  {func(eo EC2Offer) float64 { return eo.GPUMemoryGB }, Quantity.GB},
+	"price":   {func(eo EC2Offer) float64 { return eo.HourlyPrice() }, plainNumber},
+}
+
+// ec2Aliases are alternative names for searchable EC2 attributes
+var ec2Aliases = map[string]string{
+	"vcpus": "vcpu", "cpu": "vcpu", "memory": "mem", "gpus": "gpu", "gpumemory": "gpumem",
+}
+
+func plainNumber(q Quantity) (float64, error) {
+	if q.Unit != "" {
+		return 0, fmt.Errorf("Expected a plain number, got %v%s", q.Value, q.Unit)
+	}
+	return q.Value, nil
+}
+
+// ec2Predicate compiles a condition into a test of an EC2 offer
+func ec2Predicate(c Condition) (func(eo EC2Offer) bool, error) {
+	if alias, ok := ec2Aliases[c.Attr]; ok {
+		c.Attr = alias
+	}
+	if number, ok := ec2Numbers[c.Attr]; ok {
+		want, err := c.quantity(number.parse)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s: %v", c.Attr, err)
+		}
+		return func(eo EC2Offer) bool { return c.compare(number.spec(eo), want) }, nil
+	}
+	switch c.Attr {
+	case "region":
+		// applied by searchRegion
+		return func(eo EC2Offer) bool { return true }, nil
+	case "maxprice":
+		want, err := c.number()
+		if err != nil {
+			return nil, err
+		}
+		if c.Op != "=" {
+			return nil, fmt.Errorf("maxprice can only be given with =, or use price%s", c.Op)
+		}
+		return func(eo EC2Offer) bool { return eo.HourlyPrice() <= want }, nil
+	}
+	if err := c.equality(); err != nil {
+		return nil, err
+	}
+	switch c.Attr {
+	case "family":
+		// "general" matches "General purpose", "gpu" "GPU instance"
+		want := strings.ToLower(c.Value)
+		return func(eo EC2Offer) bool {
+			match := strings.HasPrefix(strings.ToLower(eo.Product.InstanceFamily), want)
+			return match == (c.Op == "=")
+		}, nil
+	case "arch":
+		want, ok := archNames[strings.ToLower(c.Value)]
+		if !ok {
+			return nil, fmt.Errorf("Unknown architecture %s, expected arm64 or x86_64", c.Value)
+		}
+		return func(eo EC2Offer) bool { return (eo.Arch == want) == (c.Op == "=") }, nil
+	case "processor":
+		// processor=graviton, processor=amd
+		return func(eo EC2Offer) bool { return c.contains(eo.Product.PhysicalProcessor) }, nil
+	case "storage":
+		// storage=nvme, storage=ebs
+		return func(eo EC2Offer) bool { return c.contains(eo.Product.Storage) }, nil
+	case "burstable":
+		want, err := strconv.ParseBool(c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("Expected true or false for burstable, got %s", c.Value)
+		}
+		return func(eo EC2Offer) bool { return (eo.Burstable == want) == (c.Op == "=") }, nil
+	}
+	return nil, fmt.Errorf("Unknown EC2 search attribute %s", c.Attr)
+}
+
+// archNames maps the names architectures go by to EC2Offer.Arch
+var archNames = map[string]string{
+	"arm64": "arm64", "arm": "arm64", "aarch64": "arm64", "graviton": "arm64",
+	"x86_64": "x86_64", "x86": "x86_64", "amd64": "x86_64",
+}
+
+// Find returns the offers of a type meeting every condition, cheapest
+// first. Offers are searched in the default region unless a region
+// condition is given.
+func (pd *PriceDB) Find(offerType OfferType, conditions []Condition) ([]Offer, error) {
+	if offerType != EC2 {
+		return nil, fmt.Errorf("Unable to search %s offers by attribute", offerType)
+	}
+	region, err := searchRegion(conditions)
+	if err != nil {
+		return nil, err
+	}
+	predicates := make([]func(eo EC2Offer) bool, 0, len(conditions))
+	for _, c := range conditions {
+		predicate, err := ec2Predicate(c)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+	results := make(OfferList, 0)
+	for param, offer := range pd.EC2 {
+		if param.Region != region {
+			continue
+		}
+		matched := true
+		for _, predicate := range predicates {
+			if !predicate(offer) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			results = append(results, offer)
+		}
+	}
+	sort.Sort(results)
+	return results, nil
+}
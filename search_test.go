@@ -0,0 +1,280 @@
+package awsprice
+
+import (
+	"strings"
+	"testing"
+)
+
+func searchDB(t *testing.T) *PriceDB {
+	db := NewPriceDB()
+	offers := []struct {
+		name   string
+		region string
+		family string
+		vcpu   string
+		memory string
+		price  float64
+	}{
+		{"m5.2xlarge", "us-west-2", "General purpose", "8", "32 GiB", 0.384},
+		{"m6g.2xlarge", "us-west-2", "General purpose", "8", "32 GiB", 0.308},
+		{"c5.2xlarge", "us-west-2", "Compute optimized", "8", "16 GiB", 0.34},
+		{"r5.2xlarge", "us-west-2", "Memory optimized", "8", "64 GiB", 0.504},
+		{"m5.xlarge", "us-west-2", "General purpose", "4", "16 GiB", 0.192},
+		{"r5.2xlarge", "eu-west-1", "Memory optimized", "8", "64 GiB", 0.564},
+		{"x1e.32xlarge", "us-west-2", "Memory optimized", "128", "3,904 GiB", 26.688},
+	}
+	for _, o := range offers {
+		offer := EC2Offer{Price: o.price, Product: EC2Attr{InstanceType: o.name,
+			InstanceFamily: o.family, VCPU: o.vcpu, Memory: o.memory}}
+		offer.parseSpecs()
+		if err := db.Store(OfferKey{Name: o.name, Attr: map[string]string{"region": o.region}}, offer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestParseSpecs(t *testing.T) {
+	offer := EC2Offer{Product: EC2Attr{InstanceType: "t4g.large", VCPU: "128", Memory: "3,904 GiB",
+		ClockSpeed: "Up to 2.5 GHz", NetworkPerformance: "Up to 12500 Megabit", DedicatedEBSThroughput: "Up to 2780 Mbps",
+		PhysicalProcessor: "AWS Graviton2 Processor", GPU: "8", GPUMemory: "640 GB"}}
+	offer.parseSpecs()
+	if offer.VCPUs != 128 || offer.MemoryGB != 3904 || offer.ClockGHz != 2.5 || offer.NetworkGbps != 12.5 ||
+		offer.EBSMbps != 2780 || offer.GPUs != 8 || offer.GPUMemoryGB != 640 {
+		t.Errorf("Unexpected specs: %+v", offer)
+	}
+	if offer.Arch != "arm64" || !offer.Burstable {
+		t.Errorf("Expected a burstable arm64 instance, got %v %v", offer.Arch, offer.Burstable)
+	}
+	offer = EC2Offer{Product: EC2Attr{InstanceType: "c6i.large", NetworkPerformance: "Moderate", GPUMemory: "NA"}}
+	offer.parseSpecs()
+	if offer.NetworkGbps != 0 || offer.GPUMemoryGB != 0 || offer.Arch != "x86_64" || offer.Burstable {
+		t.Errorf("Unexpected specs: %+v", offer)
+	}
+}
+
+func TestProductArch(t *testing.T) {
+	cases := []struct {
+		attr     EC2Attr
+		expected string
+	}{
+		{EC2Attr{ProcessorArchitecture: "64-bit Arm", PhysicalProcessor: "Ampere Altra"}, "arm64"},
+		{EC2Attr{ProcessorArchitecture: "arm64"}, "arm64"},
+		{EC2Attr{ProcessorArchitecture: "x86_64", PhysicalProcessor: "Graviton lookalike"}, "x86_64"},
+		// 64-bit says nothing, so the processor decides
+		{EC2Attr{ProcessorArchitecture: "64-bit", PhysicalProcessor: "AWS Graviton3 Processor"}, "arm64"},
+		{EC2Attr{ProcessorArchitecture: "64-bit Mac", PhysicalProcessor: "Apple M1 chip"}, "arm64"},
+		{EC2Attr{ProcessorArchitecture: "64-bit", PhysicalProcessor: "Intel Xeon Platinum 8375C"}, "x86_64"},
+	}
+	for _, c := range cases {
+		if got := productArch(c.attr); got != c.expected {
+			t.Errorf("%+v: expected %s, got %s", c.attr, c.expected, got)
+		}
+	}
+}
+
+// specDB holds the instances picked between by processor
+func specDB(t *testing.T) *PriceDB {
+	db := NewPriceDB()
+	for _, p := range []EC2Attr{
+		{InstanceType: "c6i.xlarge", PhysicalProcessor: "Intel Xeon 8375C (Ice Lake)", ClockSpeed: "3.5 GHz",
+			NetworkPerformance: "Up to 12500 Megabit", Storage: "EBS only", VCPU: "4", Memory: "8 GiB"},
+		{InstanceType: "c6a.xlarge", PhysicalProcessor: "AMD EPYC 7R13 Processor", ClockSpeed: "3.6 GHz",
+			NetworkPerformance: "Up to 12500 Megabit", Storage: "EBS only", VCPU: "4", Memory: "8 GiB"},
+		{InstanceType: "c7g.xlarge", PhysicalProcessor: "AWS Graviton3 Processor", ClockSpeed: "2.6 GHz",
+			NetworkPerformance: "Up to 12500 Megabit", Storage: "EBS only", VCPU: "4", Memory: "8 GiB"},
+		{InstanceType: "c6gd.xlarge", PhysicalProcessor: "AWS Graviton2 Processor", ClockSpeed: "2.5 GHz",
+			NetworkPerformance: "Up to 10 Gigabit", Storage: "1 x 237 NVMe SSD", VCPU: "4", Memory: "8 GiB"},
+		{InstanceType: "t4g.xlarge", PhysicalProcessor: "AWS Graviton2 Processor", ClockSpeed: "2.5 GHz",
+			NetworkPerformance: "Up to 5 Gigabit", Storage: "EBS only", VCPU: "4", Memory: "16 GiB"},
+		{InstanceType: "g5.xlarge", PhysicalProcessor: "AMD EPYC 7R32", GPU: "1", GPUMemory: "24 GB",
+			NetworkPerformance: "Up to 10 Gigabit", Storage: "1 x 250 NVMe SSD", VCPU: "4", Memory: "16 GiB"},
+	} {
+		offer := EC2Offer{Product: p, Price: float64(len(db.EC2)+1) / 10}
+		offer.parseSpecs()
+		if err := db.Store(OfferKey{Name: p.InstanceType, Attr: map[string]string{"region": "us-west-2"}}, offer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestFindSpecs(t *testing.T) {
+	db := specDB(t)
+	cases := map[string][]string{
+		"ec2(arch=arm64)":                       {"c7g.xlarge", "c6gd.xlarge", "t4g.xlarge"},
+		"ec2(arch=x86, gpu=0)":                  {"c6i.xlarge", "c6a.xlarge"},
+		"ec2(processor=amd)":                    {"c6a.xlarge", "g5.xlarge"},
+		"ec2(processor!=graviton, clock>=3.5)":  {"c6i.xlarge", "c6a.xlarge"},
+		"ec2(network>=12.5, burstable=false)":   {"c6i.xlarge", "c6a.xlarge", "c7g.xlarge"},
+		"ec2(network>=10Gbps, storage=nvme)":    {"c6gd.xlarge", "g5.xlarge"},
+		"ec2(burstable=true)":                   {"t4g.xlarge"},
+		"ec2(gpus>=1, gpumem>=16GB)":            {"g5.xlarge"},
+		"ec2(network<10000Mbps, arch=graviton)": {"t4g.xlarge"},
+	}
+	for query, expected := range cases {
+		offerType, conditions, _, err := parseSearch(query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		offers, err := db.Find(offerType, conditions)
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+			continue
+		}
+		names := make([]string, 0, len(offers))
+		for _, offer := range offers {
+			names = append(names, offer.Name())
+		}
+		if strings.Join(names, ",") != strings.Join(expected, ",") {
+			t.Errorf("%s: expected %v, got %v", query, expected, names)
+		}
+	}
+}
+
+func TestDescribeInput(t *testing.T) {
+	out, err := DescribeInput(specDB(t), "c7g.xlarge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"AWS Graviton3 Processor", "arm64", "2.6 GHz", "Up to 12500 Megabit", "EBS only", "$0.300 /hr"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "GPU") {
+		t.Errorf("Expected no GPU rows for c7g:\n%s", out)
+	}
+	if _, err := DescribeInput(specDB(t), "nope.xlarge"); err == nil {
+		t.Error("Expected an error for an unknown offer")
+	}
+}
+
+func TestFind(t *testing.T) {
+	db := searchDB(t)
+	cases := map[string][]string{
+		"ec2(vcpu>=8, mem>=32GB, family=general, maxprice=0.5)": {"m6g.2xlarge", "m5.2xlarge"},
+		"ec2(mem>=64GB)":                     {"r5.2xlarge", "x1e.32xlarge"},
+		"ec2(mem>=64GB, region=eu-west-1)":   {"r5.2xlarge"},
+		"ec2(vcpu<8)":                        {"m5.xlarge"},
+		"ec2(family!=general, price<1)":      {"c5.2xlarge", "r5.2xlarge"},
+		"ec2(mem>=1TB, family=memory)":       {"x1e.32xlarge"},
+		"ec2(vcpu=8, mem=32GB, price>=0.31)": {"m5.2xlarge"},
+	}
+	for query, expected := range cases {
+		offerType, conditions, _, err := parseSearch(query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		offers, err := db.Find(offerType, conditions)
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+			continue
+		}
+		names := make([]string, 0, len(offers))
+		for _, offer := range offers {
+			names = append(names, offer.Name())
+		}
+		if strings.Join(names, ",") != strings.Join(expected, ",") {
+			t.Errorf("%s: expected %v, got %v", query, expected, names)
+		}
+	}
+}
+
+func TestFindErrors(t *testing.T) {
+	db := searchDB(t)
+	for _, query := range []string{"ec2(colour=red)", "ec2(family>general)", "ec2(mem>=lots)", "ec2(region=mars-1)",
+		"ec2(arch=sparc)", "ec2(burstable=maybe)", "ec2(network>=fast)"} {
+		_, conditions, _, err := parseSearch(query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if _, err := db.Find(EC2, conditions); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestParseInputSearch(t *testing.T) {
+	out, err := ParseInput(searchDB(t), "ec2(mem>=64GB, region=eu-west-1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "r5.2xlarge") || strings.Contains(out, "m5") {
+		t.Errorf("Unexpected search output:\n%s", out)
+	}
+	out, err = ParseInput(searchDB(t), `/^m[56]g?\.2xlarge$/`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "m6g.2xlarge") || !strings.Contains(out, "m5.2xlarge") || strings.Contains(out, "r5") {
+		t.Errorf("Unexpected pattern output:\n%s", out)
+	}
+	if _, err := ParseInput(searchDB(t), "ec2(vcpu>=1000)"); err == nil {
+		t.Error("Expected an error when nothing matches")
+	}
+}
+
+func TestMatchName(t *testing.T) {
+	names := []string{"m5.large", "m5.xlarge", "m5a.xlarge", "m5zn.xlarge", "m5.metal", "c6g.xlarge", "c7g.large", "c7gn.large", "c5.xlarge"}
+	cases := map[string]string{
+		"m5":            "m5.large,m5.xlarge,m5a.xlarge,m5zn.xlarge,m5.metal",
+		"m5.":           "m5.large,m5.xlarge,m5.metal",
+		"m5*.xlarge":    "m5.xlarge,m5a.xlarge,m5zn.xlarge",
+		"*.metal":       "m5.metal",
+		"c?g.*":         "c6g.xlarge,c7g.large",
+		`/^c[67]g\./`:   "c6g.xlarge,c7g.large",
+		`/^(c|m)5\./`:   "m5.large,m5.xlarge,m5.metal,c5.xlarge",
+		`/xlarge$/`:     "m5.xlarge,m5a.xlarge,m5zn.xlarge,c6g.xlarge,c5.xlarge",
+		"*.2xlarge":     "",
+		"/^m5[^.]+\\./": "m5a.xlarge,m5zn.xlarge",
+	}
+	for pattern, expected := range cases {
+		match, err := MatchName(pattern)
+		if err != nil {
+			t.Errorf("%s: %v", pattern, err)
+			continue
+		}
+		matched := make([]string, 0)
+		for _, name := range names {
+			if match(name) {
+				matched = append(matched, name)
+			}
+		}
+		if strings.Join(matched, ",") != expected {
+			t.Errorf("%s: expected %s, got %v", pattern, expected, matched)
+		}
+	}
+	for _, pattern := range []string{"/^c[67/", "m5[.xlarge"} {
+		if _, err := MatchName(pattern); err == nil {
+			t.Errorf("%s: expected an invalid pattern error", pattern)
+		}
+	}
+}
+
+func TestSearchOrder(t *testing.T) {
+	db := NewPriceDB()
+	oregon := map[string]string{"region": "us-west-2"}
+	for name, price := range map[string]float64{"m5.xlarge": 0.192, "m5a.xlarge": 0.172, "m5d.xlarge": 0.226,
+		"m5n.xlarge": 0.238, "m5zn.xlarge": 0.3303, "r5.xlarge": 0.252, "m6i.xlarge": 0.192} {
+		if err := db.Store(OfferKey{Name: name, Attr: oregon}, EC2Offer{Price: price, Product: EC2Attr{InstanceType: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	offers, err := db.Search(Query{Name: "m*.xlarge", Attr: oregon})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(offers))
+	for _, offer := range offers {
+		names = append(names, offer.Name())
+	}
+	// equal prices fall back to name order
+	expected := "m5a.xlarge,m5.xlarge,m6i.xlarge,m5d.xlarge,m5n.xlarge,m5zn.xlarge"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("Expected %s, got %v", expected, names)
+	}
+	if _, err := db.Search(Query{Name: "/[/", Attr: oregon}); err == nil {
+		t.Error("Expected an error for an invalid regex")
+	}
+}
@@ -0,0 +1,165 @@
+package awsprice
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// snapshotFile is the snapshot compiled in with the embedsnapshot build
+// tag; 'make embedded' writes it from the current cache
+const snapshotFile = "snapshot.gob.gz"
+
+// SnapshotFilter picks the regions and offer types kept in a snapshot.
+// Empty fields keep everything.
+type SnapshotFilter struct {
+	Regions []Region
+	Types   []OfferType
+}
+
+func (sf SnapshotFilter) keeps(offerType OfferType, region Region) bool {
+	return sf.keepsType(offerType) && sf.keepsRegion(region)
+}
+
+func (sf SnapshotFilter) keepsType(offerType OfferType) bool {
+	if len(sf.Types) == 0 {
+		return true
+	}
+	for _, t := range sf.Types {
+		if t == offerType {
+			return true
+		}
+	}
+	return false
+}
+
+func (sf SnapshotFilter) keepsRegion(region Region) bool {
+	if len(sf.Regions) == 0 {
+		return true
+	}
+	for _, r := range sf.Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// subset returns a DB holding just the offers a filter keeps, along
+// with the spot history and savings plan rates that apply to them
+func (pd *PriceDB) subset(filter SnapshotFilter) *PriceDB {
+	sub := NewPriceDB()
+	sub.Header = pd.Header
+	for param, offer := range pd.EC2 {
+		if filter.keeps(EC2, param.Region) {
+			sub.EC2[param] = offer
+			sub.register(param.Name, EC2)
+		}
+	}
+	for param, offer := range pd.RDS {
+		if filter.keeps(RDS, param.Region) {
+			sub.RDS[param] = offer
+			sub.register(param.Name, RDS)
+		}
+	}
+	for param, offer := range pd.OpenSearch {
+		if filter.keeps(OpenSearch, param.Region) {
+			sub.OpenSearch[param] = offer
+			sub.register(param.Name, OpenSearch)
+		}
+	}
+	for param, offer := range pd.Redshift {
+		if filter.keeps(Redshift, param.Region) {
+			sub.Redshift[param] = offer
+			sub.register(param.Name, Redshift)
+		}
+	}
+	for param, offer := range pd.MSK {
+		if filter.keeps(MSK, param.Region) {
+			sub.MSK[param] = offer
+			sub.register(param.Name, MSK)
+		}
+	}
+	for param, offer := range pd.EFS {
+		if filter.keeps(EFS, param.Region) {
+			sub.EFS[param] = offer
+		}
+	}
+	for param, offer := range pd.FSx {
+		if filter.keeps(FSx, param.Region) {
+			sub.FSx[param] = offer
+		}
+	}
+	for param, offer := range pd.Fargate {
+		if filter.keeps(Fargate, param.Region) {
+			sub.Fargate[param] = offer
+		}
+	}
+	for param, offer := range pd.EKS {
+		if filter.keeps(EKS, param.Region) {
+			sub.EKS[param] = offer
+		}
+	}
+	// the names of types without a name in their params, like efs
+	for _, offerType := range []OfferType{EFS, FSx, Fargate, EKS} {
+		if filter.keepsType(offerType) {
+			sub.carryLookup(pd, offerType)
+		}
+	}
+	for key, observations := range pd.Spot {
+		if region, err := zoneRegion(key.AvailabilityZone); err == nil && filter.keeps(EC2, region) {
+			sub.Spot[key] = observations
+		}
+	}
+	for key, rate := range pd.SavingsPlans {
+		offerType := SavingsPlan
+		switch key.Service {
+		case "ec2":
+			offerType = EC2
+		case "fargate":
+			offerType = Fargate
+		}
+		if filter.keeps(offerType, key.Region) {
+			sub.SavingsPlans[key] = rate
+		}
+	}
+	return sub
+}
+
+// WriteSnapshot writes the offers of a DB kept by a filter as a
+// compressed snapshot, for ReadSnapshot or to be embedded in a binary
+func WriteSnapshot(w io.Writer, pd *PriceDB, filter SnapshotFilter) error {
+	zw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if err := pd.subset(filter).encode(zw); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot. name is
+// reported in a ParseError.
+func ReadSnapshot(r io.Reader, name string) (*PriceDB, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, &ParseError{File: name, Err: err}
+	}
+	defer zr.Close()
+	return decodePriceDB(zr, name)
+}
+
+// EmbeddedSnapshot returns the snapshot compiled into the binary with
+// the embedsnapshot build tag, or a MissingError if there isn't one
+func EmbeddedSnapshot() (*PriceDB, error) {
+	if len(embeddedSnapshot) == 0 {
+		return nil, &MissingError{What: "embedded price snapshot"}
+	}
+	db, err := ReadSnapshot(bytes.NewReader(embeddedSnapshot), snapshotFile)
+	if err != nil {
+		return nil, err
+	}
+	db.Header.Embedded = true
+	return db, nil
+}
@@ -0,0 +1,11 @@
+//go:build embedsnapshot
+// +build embedsnapshot
+
+package awsprice
+
+import _ "embed" // for the snapshot
+
+// embeddedSnapshot is the compressed DB written by 'make embedded'
+//
+//go:embed snapshot.gob.gz
+var embeddedSnapshot []byte
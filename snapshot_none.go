@@ -0,0 +1,7 @@
+//go:build !embedsnapshot
+// +build !embedsnapshot
+
+package awsprice
+
+// embeddedSnapshot is empty unless built with the embedsnapshot tag
+var embeddedSnapshot []byte
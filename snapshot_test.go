@@ -0,0 +1,63 @@
+package awsprice
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSnapshotSubset(t *testing.T) {
+	db := backendDB(t)
+	db.Header.Published = map[string]string{"AmazonEC2": "2023-01-01T00:00:00Z"}
+	region, _ := NewRegion("us-west-2")
+	var b bytes.Buffer
+	if err := WriteSnapshot(&b, db, SnapshotFilter{Regions: []Region{region}, Types: []OfferType{EC2}}); err != nil {
+		t.Fatalf("Error writing snapshot: %v", err)
+	}
+	snapshot, err := ReadSnapshot(&b, "test snapshot")
+	if err != nil {
+		t.Fatalf("Error reading snapshot: %v", err)
+	}
+	if snapshot.Header.PublicationDate() != "2023-01-01T00:00:00Z" {
+		t.Errorf("Expected the publication date to be kept, got %+v", snapshot.Header)
+	}
+	if _, err := snapshot.Get(Query{Name: "r5.2xlarge", Attr: map[string]string{"region": "eu-west-1"}}); err == nil {
+		t.Error("Expected eu-west-1 to be left out")
+	}
+	for _, attr := range []map[string]string{{}, {"market": "spot"}, {"sp": "compute"}} {
+		if _, err := snapshot.Get(Query{Name: "m5.xlarge", Attr: attr}); err != nil {
+			t.Errorf("Expected m5.xlarge %v in the snapshot: %v", attr, err)
+		}
+	}
+	// the us-east-1 spot prices and the fargate savings plan rate are dropped
+	if len(snapshot.Spot) != 2 || len(snapshot.SavingsPlans) != 1 {
+		t.Errorf("Expected 2 spot zones and 1 savings plan rate, got %d and %d", len(snapshot.Spot), len(snapshot.SavingsPlans))
+	}
+}
+
+func TestOpenPricerEmbedded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsprice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var b bytes.Buffer
+	if err := WriteSnapshot(&b, backendDB(t), SnapshotFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	defer func(old []byte) { embeddedSnapshot = old }(embeddedSnapshot)
+	embeddedSnapshot = b.Bytes()
+
+	client := &Client{CacheDir: dir, Backend: BoltBackend{}}
+	pricer, err := client.OpenPricer()
+	if err != nil {
+		t.Fatalf("Expected the embedded snapshot, got %v", err)
+	}
+	if db, ok := pricer.(*PriceDB); !ok || !db.Header.Embedded {
+		t.Errorf("Expected the embedded snapshot, got %T", pricer)
+	}
+	if _, err := pricer.Get(Query{Name: "m5.xlarge"}); err != nil {
+		t.Errorf("Expected m5.xlarge in the embedded snapshot: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package awsprice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpotPriceHistory is the document produced by
+// `aws ec2 describe-spot-price-history`
+type SpotPriceHistory struct {
+	SpotPriceHistory []SpotPriceEntry `json:"SpotPriceHistory"`
+}
+
+// SpotPriceEntry is a single observation in a SpotPriceHistory
+type SpotPriceEntry struct {
+	AvailabilityZone   string    `json:"AvailabilityZone"`
+	InstanceType       string    `json:"InstanceType"`
+	ProductDescription string    `json:"ProductDescription"`
+	SpotPrice          string    `json:"SpotPrice"`
+	Timestamp          time.Time `json:"Timestamp"`
+}
+
+// SpotKey identifies a series of spot price observations
+type SpotKey struct {
+	InstanceType       string
+	AvailabilityZone   string
+	ProductDescription string
+}
+
+// SpotObservation is a spot price seen at a point in time
+type SpotObservation struct {
+	Timestamp time.Time
+	Price     float64
+}
+
+// defaultSpotProduct matches the Linux/Shared EC2 offers we extract
+const defaultSpotProduct = "Linux/UNIX"
+
+// AddSpotHistory reads describe-spot-price-history JSON documents from r
+// and stores their observations, returning how many were added. Nothing
+// is stored unless every document and price parses.
+func (pd *PriceDB) AddSpotHistory(r io.Reader) (int, error) {
+	decoder := json.NewDecoder(r)
+	parsed := make(map[SpotKey][]SpotObservation)
+	added := 0
+	for {
+		var history SpotPriceHistory
+		err := decoder.Decode(&history)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("Unable to parse spot price history: %v", err)
+		}
+		for _, entry := range history.SpotPriceHistory {
+			price, err := strconv.ParseFloat(entry.SpotPrice, 64)
+			if err != nil {
+				return 0, fmt.Errorf("Unable to parse spot price %q for %s", entry.SpotPrice, entry.InstanceType)
+			}
+			key := SpotKey{entry.InstanceType, entry.AvailabilityZone, entry.ProductDescription}
+			parsed[key] = append(parsed[key], SpotObservation{Timestamp: entry.Timestamp, Price: price})
+			added++
+		}
+	}
+	for key, observations := range parsed {
+		pd.Spot[key] = dedupeSpot(append(pd.Spot[key], observations...))
+	}
+	return added, nil
+}
+
+// dedupeSpot sorts observations by time, dropping repeated timestamps
+// from overlapping imports
+func dedupeSpot(observations []SpotObservation) []SpotObservation {
+	sort.Slice(observations, func(i, j int) bool {
+		return observations[i].Timestamp.Before(observations[j].Timestamp)
+	})
+	deduped := observations[:0]
+	for i, o := range observations {
+		if i > 0 && o.Timestamp.Equal(observations[i-1].Timestamp) {
+			continue
+		}
+		deduped = append(deduped, o)
+	}
+	return deduped
+}
+
+// zoneRegion returns the region an availability zone such as
+// us-east-1a belongs to
+func zoneRegion(zone string) (Region, error) {
+	return NewRegion(strings.TrimRight(zone, "abcdefghijklmnopqrstuvwxyz"))
+}
+
+// spotAttr fills in the region of a spot query from its availability
+// zone when only the zone is given, and checks the two agree when both
+// are
+func spotAttr(attr map[string]string) (map[string]string, error) {
+	az, ok := attr["az"]
+	if !ok {
+		return attr, nil
+	}
+	zoneReg, err := zoneRegion(az)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown availability zone %s", az)
+	}
+	if given, ok := attr["region"]; ok {
+		region, err := NewRegion(given)
+		if err != nil {
+			return nil, err
+		}
+		if region != zoneReg {
+			return nil, fmt.Errorf("Availability zone %s is not in region %s", az, given)
+		}
+		return attr, nil
+	}
+	filled := map[string]string{"region": string(zoneReg)}
+	for k, v := range attr {
+		filled[k] = v
+	}
+	return filled, nil
+}
+
+// spotPrices collects all the observed prices for an instance type in
+// a region, optionally limited to one availability zone
+func (pd *PriceDB) spotPrices(name string, region Region, attr map[string]string) ([]float64, []string) {
+	product := defaultSpotProduct
+	if given, ok := attr["product"]; ok {
+		product = given
+	}
+	prices := make([]float64, 0)
+	zones := make([]string, 0)
+	for key, observations := range pd.Spot {
+		if key.InstanceType != name || key.ProductDescription != product {
+			continue
+		}
+		if az, ok := attr["az"]; ok && key.AvailabilityZone != az {
+			continue
+		}
+		if zoneReg, err := zoneRegion(key.AvailabilityZone); err != nil || zoneReg != region {
+			continue
+		}
+		zones = append(zones, key.AvailabilityZone)
+		for _, o := range observations {
+			prices = append(prices, o.Price)
+		}
+	}
+	sort.Strings(zones)
+	return prices, zones
+}
+
+// spotStat reduces a set of prices to a single value. stat may be
+// min, max, mean, or a percentile such as p50 or p90.
+func spotStat(prices []float64, stat string) (float64, error) {
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("No spot price history")
+	}
+	sorted := append([]float64(nil), prices...)
+	sort.Float64s(sorted)
+	switch stat {
+	case "min":
+		return sorted[0], nil
+	case "max":
+		return sorted[len(sorted)-1], nil
+	case "mean":
+		sum := 0.0
+		for _, p := range sorted {
+			sum += p
+		}
+		return sum / float64(len(sorted)), nil
+	}
+	if !strings.HasPrefix(stat, "p") {
+		return 0, fmt.Errorf("Unknown spot statistic %s", stat)
+	}
+	pct, err := strconv.ParseFloat(stat[1:], 64)
+	if err != nil || pct <= 0 || pct > 100 {
+		return 0, fmt.Errorf("Unknown spot statistic %s", stat)
+	}
+	// nearest-rank percentile
+	rank := int(math.Ceil(pct / 100 * float64(len(sorted))))
+	return sorted[rank-1], nil
+}
+
+// spotOffer prices an EC2 offer from the imported spot history
+func (pd *PriceDB) spotOffer(onDemand EC2Offer, param EC2OfferParam, attr map[string]string) (Offer, error) {
+	stat := "p50"
+	if given, ok := attr["stat"]; ok {
+		stat = strings.ToLower(given)
+	}
+	prices, zones := pd.spotPrices(param.Name, param.Region, attr)
+	price, err := spotStat(prices, stat)
+	if err != nil {
+		return nil, fmt.Errorf("%v for %s in %s", err, param.Name, param.Region)
+	}
+	return SpotOffer{OnDemand: onDemand, Price: price, Stat: stat, Zones: zones, Samples: len(prices)}, nil
+}
+
+// ImportSpot adds a describe-spot-price-history JSON file to the
+// summary DB, creating the DB if it doesn't exist yet.
+func (c *Client) ImportSpot(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// a DB that can't be read is left alone, rather than replaced by
+	// one holding just the spot history
+	priceDB, err := c.LoadPriceDB()
+	var missing *MissingError
+	if errors.As(err, &missing) {
+		priceDB = NewPriceDB()
+	} else if err != nil {
+		return err
+	}
+	added, err := priceDB.AddSpotHistory(file)
+	if err != nil {
+		return err
+	}
+	if err := c.makeCacheDir(); err != nil {
+		return err
+	}
+	c.logf("Imported %d spot prices from %s\n", added, path)
+	return c.saveDB(priceDB)
+}
@@ -0,0 +1,118 @@
+package awsprice
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const spotHistory = `{"SpotPriceHistory": [
+	{"AvailabilityZone": "us-west-2a", "InstanceType": "m5.xlarge", "ProductDescription": "Linux/UNIX", "SpotPrice": "0.070000", "Timestamp": "2023-01-01T00:00:00Z"},
+	{"AvailabilityZone": "us-west-2a", "InstanceType": "m5.xlarge", "ProductDescription": "Linux/UNIX", "SpotPrice": "0.080000", "Timestamp": "2023-01-01T01:00:00Z"},
+	{"AvailabilityZone": "us-west-2b", "InstanceType": "m5.xlarge", "ProductDescription": "Linux/UNIX", "SpotPrice": "0.090000", "Timestamp": "2023-01-01T00:00:00Z"},
+	{"AvailabilityZone": "us-west-2b", "InstanceType": "m5.xlarge", "ProductDescription": "Linux/UNIX", "SpotPrice": "0.090000", "Timestamp": "2023-01-01T00:00:00Z"},
+	{"AvailabilityZone": "us-east-1a", "InstanceType": "m5.xlarge", "ProductDescription": "Linux/UNIX", "SpotPrice": "0.500000", "Timestamp": "2023-01-01T00:00:00Z"}
+]}`
+
+func TestSpotOffer(t *testing.T) {
+	db := NewPriceDB()
+	err := db.Store(OfferKey{Name: "m5.xlarge", Attr: map[string]string{"region": "us-west-2"}}, EC2Offer{Price: 0.192})
+	if err != nil {
+		t.Fatalf("Error storing EC2 offer: %v", err)
+	}
+	added, err := db.AddSpotHistory(strings.NewReader(spotHistory))
+	if err != nil {
+		t.Fatalf("Error importing spot history: %v", err)
+	}
+	if added != 5 {
+		t.Errorf("Expected 5 observations, got %d", added)
+	}
+
+	offer, err := db.Get(Query{Name: "m5.xlarge", Attr: map[string]string{"market": "spot", "stat": "p90"}})
+	if err != nil {
+		t.Fatalf("Error getting spot offer: %v", err)
+	}
+	// the duplicate us-west-2b sample is dropped and us-east-1a is ignored
+	if offer.HourlyPrice() != 0.09 {
+		t.Errorf("Expected p90 of 0.09, got %v", offer.HourlyPrice())
+	}
+	spot := offer.(SpotOffer)
+	if spot.Samples != 3 {
+		t.Errorf("Expected 3 samples, got %d", spot.Samples)
+	}
+
+	offer, err = db.Get(Query{Name: "m5.xlarge", Attr: map[string]string{"market": "spot", "stat": "min", "az": "us-west-2a"}})
+	if err != nil || offer.HourlyPrice() != 0.07 {
+		t.Errorf("Expected min of 0.07 in us-west-2a, got %v (%v)", offer, err)
+	}
+
+	// the zone alone picks the region
+	err = db.Store(OfferKey{Name: "m5.xlarge", Attr: map[string]string{"region": "us-east-1"}}, EC2Offer{Price: 0.192})
+	if err != nil {
+		t.Fatalf("Error storing EC2 offer: %v", err)
+	}
+	offer, err = db.Get(Query{Name: "m5.xlarge", Attr: map[string]string{"market": "spot", "az": "us-east-1a"}})
+	if err != nil || offer.HourlyPrice() != 0.5 {
+		t.Errorf("Expected 0.5 in us-east-1a, got %v (%v)", offer, err)
+	}
+	_, err = db.Get(Query{Name: "m5.xlarge", Attr: map[string]string{"market": "spot", "az": "us-east-1a", "region": "us-west-2"}})
+	if err == nil || !strings.Contains(err.Error(), "not in region") {
+		t.Errorf("Expected an error for a zone outside the region, got %v", err)
+	}
+}
+
+func TestImportSpotCorruptDB(t *testing.T) {
+	client, cleanup := testClient(t, DefaultEndpoint)
+	defer cleanup()
+	if err := client.makeCacheDir(); err != nil {
+		t.Fatal(err)
+	}
+	history := filepath.Join(client.CacheDir, "spot.json")
+	if err := ioutil.WriteFile(history, []byte(spotHistory), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// with no DB yet, one is started
+	if err := client.ImportSpot(history); err != nil {
+		t.Fatalf("Error importing into a new DB: %v", err)
+	}
+	if _, err := client.LoadPriceDB(); err != nil {
+		t.Errorf("Expected a DB holding the spot history: %v", err)
+	}
+
+	path := client.path(summaryDBFile)
+	if err := ioutil.WriteFile(path, []byte("not a gob"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ImportSpot(history); err == nil {
+		t.Error("Expected an error importing into a corrupt DB")
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "not a gob" {
+		t.Errorf("Expected the corrupt DB to be left alone, got %q (%v)", data, err)
+	}
+}
+
+func TestAddSpotHistoryBadPrice(t *testing.T) {
+	db := NewPriceDB()
+	if _, err := db.AddSpotHistory(strings.NewReader(spotHistory)); err != nil {
+		t.Fatal(err)
+	}
+	before := make(map[SpotKey][]SpotObservation)
+	for key, observations := range db.Spot {
+		before[key] = append([]SpotObservation(nil), observations...)
+	}
+
+	// a good observation ahead of the bad one must not be stored either
+	history := `{"SpotPriceHistory": [{"AvailabilityZone": "us-west-2c", "InstanceType": "m5.xlarge",
+		"ProductDescription": "Linux/UNIX", "SpotPrice": "0.05", "Timestamp": "2023-01-01T00:00:00Z"},
+		{"AvailabilityZone": "us-west-2a", "InstanceType": "m5.xlarge",
+		"ProductDescription": "Linux/UNIX", "SpotPrice": "cheap", "Timestamp": "2023-01-01T00:00:00Z"}]}`
+	if _, err := db.AddSpotHistory(strings.NewReader(history)); err == nil || !strings.Contains(err.Error(), "cheap") {
+		t.Errorf("Expected an error for the unparseable price, got %v", err)
+	}
+	if !reflect.DeepEqual(db.Spot, before) {
+		t.Errorf("Expected a failed import to leave the DB unchanged, got %v", db.Spot)
+	}
+}
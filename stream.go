@@ -0,0 +1,231 @@
+package awsprice
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* Offer files run to gigabytes (EC2 especially), so rather than
+ * unmarshalling one whole, they're walked token by token. Only a single
+ * product, or the on-demand terms of a single SKU, is decoded at a time;
+ * reserved terms and anything else unused are skipped without decoding.
+ */
+
+// streamOfferFile walks the offer file at path, which may be JSON or
+// CSV. See streamOffer.
+func streamOfferFile(path string, keep func(p Product) bool) ([]Product, map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	if strings.HasSuffix(path, ".csv") {
+		return streamOfferCSV(file, keep)
+	}
+	return streamOffer(file, keep)
+}
+
+// streamOffer walks an offer document, returning the products that keep
+// accepts and their on-demand prices. Other products are dropped as
+// they're read, and their terms are skipped.
+func streamOffer(r io.Reader, keep func(p Product) bool) ([]Product, map[string]float64, error) {
+	dec := json.NewDecoder(r)
+	products := make([]Product, 0)
+	kept := make(map[string]bool)
+	seenProducts := false
+	prices := make(map[string]float64)
+	onDemand := func(sku string) error {
+		if seenProducts && !kept[sku] {
+			return skipValue(dec)
+		}
+		var terms map[string]TermItem
+		if err := dec.Decode(&terms); err != nil {
+			return err
+		}
+		price, err := simplePrice(terms)
+		if err != nil {
+			// reported as unpriced once the products are read
+			return nil
+		}
+		prices[sku] = price
+		return nil
+	}
+	err := eachMember(dec, func(key string) error {
+		switch key {
+		case "products":
+			defer func() { seenProducts = true }()
+			return eachMember(dec, func(string) error {
+				var p Product
+				if err := dec.Decode(&p); err != nil {
+					return err
+				}
+				p.normalize()
+				if keep(p) {
+					products = append(products, p)
+					kept[p.SKU] = true
+				}
+				return nil
+			})
+		case "terms":
+			return eachMember(dec, func(termType string) error {
+				if termType != "OnDemand" {
+					return skipValue(dec)
+				}
+				return eachMember(dec, onDemand)
+			})
+		default:
+			return skipValue(dec)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// terms listed ahead of products were all priced, so drop the extras
+	for sku := range prices {
+		if !kept[sku] {
+			delete(prices, sku)
+		}
+	}
+	return products, prices, nil
+}
+
+// eachMember reads a JSON object, calling fn with each key while the
+// decoder is positioned at its value. fn must consume the value.
+func eachMember(dec *json.Decoder, fn func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("Expected an object key, got %v", tok)
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// expectDelim reads the next token, which must be the given delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("Expected %v in offer file, got %v", delim, tok)
+	}
+	return nil
+}
+
+// skipValue consumes the next value, however deeply nested, without
+// decoding it
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// csvTermColumns are the CSV offer columns describing a term rather
+// than a product attribute
+var csvTermColumns = map[string]bool{
+	"SKU": true, "OfferTermCode": true, "RateCode": true, "TermType": true,
+	"PriceDescription": true, "EffectiveDate": true, "StartingRange": true,
+	"EndingRange": true, "Unit": true, "PricePerUnit": true, "Currency": true,
+	"LeaseContractLength": true, "PurchaseOption": true, "OfferingClass": true,
+	"Product Family": true,
+}
+
+// streamOfferCSV is streamOffer for the CSV form of an offer file, where
+// each row is one price dimension of one term, repeating the product's
+// attributes. Attribute headers like "Instance Type" become names like
+// "instancetype", matching the normalized JSON attribute names.
+func streamOfferCSV(r io.Reader, keep func(p Product) bool) ([]Product, map[string]float64, error) {
+	reader := csv.NewReader(r)
+	// the metadata rows ahead of the header have just two fields
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	var header []string
+	column := make(map[string]int)
+	products := make([]Product, 0)
+	seen := make(map[string]bool)
+	kept := make(map[string]bool)
+	prices := make(map[string]float64)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if header == nil {
+			if len(row) > 0 && row[0] == "SKU" {
+				header = append([]string(nil), row...)
+				for i, name := range header {
+					column[name] = i
+				}
+				for _, name := range []string{"TermType", "PricePerUnit", "Currency", "Product Family"} {
+					if _, ok := column[name]; !ok {
+						return nil, nil, fmt.Errorf("No %s column in offer CSV", name)
+					}
+				}
+			}
+			continue
+		}
+		if len(row) != len(header) {
+			return nil, nil, fmt.Errorf("Expected %d columns in offer CSV, got %d", len(header), len(row))
+		}
+		sku := row[column["SKU"]]
+		if !seen[sku] {
+			seen[sku] = true
+			p := Product{SKU: sku, ProductFamily: row[column["Product Family"]],
+				Attributes: make(map[string]string)}
+			for i, name := range header {
+				if !csvTermColumns[name] {
+					p.Attributes[strings.ToLower(strings.Replace(name, " ", "", -1))] = row[i]
+				}
+			}
+			if keep(p) {
+				products = append(products, p)
+				kept[sku] = true
+			}
+		}
+		if !kept[sku] || row[column["TermType"]] != "OnDemand" || row[column["Currency"]] != "USD" {
+			continue
+		}
+		if _, ok := prices[sku]; ok {
+			continue
+		}
+		if price, err := strconv.ParseFloat(row[column["PricePerUnit"]], 64); err == nil {
+			prices[sku] = price
+		}
+	}
+	if header == nil {
+		return nil, nil, fmt.Errorf("No header row in offer CSV")
+	}
+	return products, prices, nil
+}
@@ -0,0 +1,182 @@
+package awsprice
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// terms come first here, and include reserved terms and other values
+// to be skipped
+const streamOfferDoc = `{
+	"formatVersion": "v1.0",
+	"skipped": [1, [2, {"x": 3}]],
+	"terms": {
+		"Reserved": {"SKU1": {"SKU1.R": {"priceDimensions": {"R.1": {"pricePerUnit": {"USD": "9.0"}}}}}},
+		"OnDemand": {
+			"SKU1": {"SKU1.OD": {"priceDimensions": {"OD.1": {"pricePerUnit": {"USD": "0.2000000000"}}}}},
+			"SKU2": {"SKU2.OD": {"priceDimensions": {"OD.1": {"pricePerUnit": {"USD": "0.4"}}}}}
+		}
+	},
+	"products": {
+		"SKU1": {"sku": "SKU1", "attributes": {"instanceType": "m4.xlarge"}},
+		"SKU2": {"sku": "SKU2", "attributes": {"instanceType": "m4.2xlarge"}}
+	}
+}`
+
+func TestStreamOffer(t *testing.T) {
+	var types []string
+	products, prices, err := streamOffer(strings.NewReader(streamOfferDoc), func(p Product) bool {
+		types = append(types, p.Attr("instanceType"))
+		return p.Attr("instanceType") == "m4.xlarge"
+	})
+	if err != nil {
+		t.Fatalf("Error streaming offer: %v", err)
+	}
+	if len(types) != 2 {
+		t.Errorf("Expected 2 products, got %v", types)
+	}
+	if len(products) != 1 || products[0].SKU != "SKU1" {
+		t.Errorf("Expected only SKU1 to be kept, got %+v", products)
+	}
+	if len(prices) != 1 || prices["SKU1"] != 0.2 {
+		t.Errorf("Expected only SKU1 at 0.2, got %v", prices)
+	}
+}
+
+func TestStreamOfferTruncated(t *testing.T) {
+	doc := streamOfferDoc[:len(streamOfferDoc)/2]
+	_, _, err := streamOffer(strings.NewReader(doc), func(p Product) bool { return true })
+	if err == nil {
+		t.Errorf("Expected an error for a truncated offer file")
+	}
+}
+
+// writeSyntheticEC2 writes an EC2 offer file with n products, a quarter
+// of which are Linux/Shared, each with on-demand and reserved terms
+func writeSyntheticEC2(path string, n int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	oses := []string{"Linux", "Windows", "RHEL", "SUSE"}
+	fmt.Fprint(w, `{"formatVersion":"v1.0","publicationDate":"2024-01-01T00:00:00Z","products":{`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprintf(w, `"SKU%d":{"sku":"SKU%d","productFamily":"Compute Instance","attributes":{"servicecode":"AmazonEC2","location":"US West (Oregon)","instanceType":"x%d.large","vcpu":"2","memory":"8 GiB","operatingSystem":"%s","tenancy":"Shared"}}`,
+			i, i, i, oses[i%len(oses)])
+	}
+	fmt.Fprint(w, `},"terms":{"OnDemand":{`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprintf(w, `"SKU%d":{"SKU%d.OD":{"offerTermCode":"OD","sku":"SKU%d","priceDimensions":{"SKU%d.OD.1":{"rateCode":"SKU%d.OD.1","description":"on demand","unit":"Hrs","pricePerUnit":{"USD":"0.%04d"}}}}}`,
+			i, i, i, i, i, i%10000)
+	}
+	fmt.Fprint(w, `},"Reserved":{`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprintf(w, `"SKU%d":{`, i)
+		for j := 0; j < 6; j++ {
+			if j > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `"SKU%d.R%d":{"offerTermCode":"R%d","sku":"SKU%d","termAttributes":{"LeaseContractLength":"1yr","PurchaseOption":"All Upfront"},"priceDimensions":{"SKU%d.R%d.1":{"unit":"Quantity","pricePerUnit":{"USD":"1000"}}}}`,
+				i, j, j, i, i, j)
+		}
+		fmt.Fprint(w, "}")
+	}
+	fmt.Fprint(w, "}}}")
+	return w.Flush()
+}
+
+// peakHeap runs fn while sampling the heap, returning the most seen in use
+func peakHeap(fn func()) uint64 {
+	runtime.GC()
+	var peak uint64
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		var stats runtime.MemStats
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		defer close(sampled)
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapInuse > peak {
+				peak = stats.HeapInuse
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	fn()
+	close(done)
+	<-sampled
+	return peak
+}
+
+func benchmarkExtract(b *testing.B, extract func(client *Client, path string)) {
+	dir, err := ioutil.TempDir("", "awsprice-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	client := &Client{CacheDir: dir}
+	path := client.path("AmazonEC2.json")
+	if err := writeSyntheticEC2(path, 40000); err != nil {
+		b.Fatal(err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		b.SetBytes(info.Size())
+	}
+	b.ResetTimer()
+	var peak uint64
+	for i := 0; i < b.N; i++ {
+		if p := peakHeap(func() { extract(client, path) }); p > peak {
+			peak = p
+		}
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+}
+
+// BenchmarkExtractEC2 streams a large synthetic offer file. Compare its
+// peak-heap-MB with BenchmarkExtractEC2Unmarshal, which reads the file
+// whole as the extractors used to.
+func BenchmarkExtractEC2(b *testing.B) {
+	benchmarkExtract(b, func(client *Client, path string) {
+		if err := ec2Extractor.Extract(context.Background(), client, NewPriceDB()); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkExtractEC2Unmarshal(b *testing.B) {
+	benchmarkExtract(b, func(client *Client, path string) {
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var offerIndex OfferFile
+		if err := json.Unmarshal(file, &offerIndex); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
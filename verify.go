@@ -0,0 +1,150 @@
+package awsprice
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/* A download can be cut short, or a save interrupted, leaving a file
+ * that looks complete but isn't. Fetched files are checked before they
+ * replace the cached copy, and files we write are written aside and
+ * renamed into place, so the cache only ever holds whole files.
+ */
+
+// verifyOfferFile checks the file fetched for dl, at path, before it's
+// moved into the cache. JSON must be well formed and CSV rows complete,
+// and if dl names an offer code the file must be that offer's. It
+// returns the file's size and SHA-256 for the manifest.
+func verifyOfferFile(path string, dl offerDownload) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	r := io.TeeReader(file, hash)
+	var code string
+	if strings.HasSuffix(dl.Filename, ".csv") {
+		code, err = verifyOfferCSV(r)
+	} else {
+		code, err = verifyOfferJSON(r)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	if dl.OfferCode != "" && code != dl.OfferCode {
+		return 0, "", fmt.Errorf("Expected an offer file for %s, got %q", dl.OfferCode, code)
+	}
+	// hash anything the decoder didn't need to read
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return 0, "", err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+	return info.Size(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// fileSHA256 returns the hex SHA-256 of a file's contents
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyOfferJSON walks a JSON document to its end without decoding it,
+// returning the top level offerCode, if any
+func verifyOfferJSON(r io.Reader) (string, error) {
+	dec := json.NewDecoder(r)
+	var code string
+	err := eachMember(dec, func(key string) error {
+		if key == "offerCode" {
+			return dec.Decode(&code)
+		}
+		return skipValue(dec)
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return "", fmt.Errorf("Unexpected data after the end of the document")
+	}
+	return code, nil
+}
+
+// verifyOfferCSV reads every row of a CSV offer file, checking each has
+// as many columns as the header, and returns its OfferCode
+func verifyOfferCSV(r io.Reader) (string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	var code string
+	columns := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if columns > 0 {
+			if len(row) != columns {
+				return "", fmt.Errorf("Expected %d columns in offer CSV, got %d", columns, len(row))
+			}
+			continue
+		}
+		switch {
+		case len(row) == 2 && row[0] == "OfferCode":
+			code = row[1]
+		case len(row) > 0 && row[0] == "SKU":
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return "", fmt.Errorf("No header row in offer CSV")
+	}
+	return code, nil
+}
+
+// replaceFile writes a file by way of a temporary file in the same
+// directory, which is synced and then renamed over path. Readers see
+// either the old file or the whole new one, never a partial write.
+func replaceFile(path string, write func(w io.Writer) error) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	// a no-op once the rename has happened
+	defer os.Remove(tmp.Name())
+	err = write(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
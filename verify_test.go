@@ -0,0 +1,163 @@
+package awsprice
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyOfferFile(t *testing.T) {
+	ec2 := offerDownload{Filename: "AmazonEC2.json", OfferCode: "AmazonEC2"}
+	cases := []struct {
+		path string
+		dl   offerDownload
+		ok   bool
+	}{
+		{"testdata/offers/v1.0/aws/AmazonEC2/current/index.json", ec2, true},
+		{"testdata/offers/v1.0/aws/AmazonRDS/current/index.json", ec2, false},
+		{"testdata/offers/v1.0/aws/index.json", offerDownload{Filename: "offer.json"}, true},
+		{"testdata/offers/v1.0/aws/AmazonEC2/current/index.csv", offerDownload{Filename: "AmazonEC2.csv", OfferCode: "AmazonEC2"}, true},
+		{"testdata/offers/v1.0/aws/AmazonRDS/current/index.csv", offerDownload{Filename: "AmazonEC2.csv", OfferCode: "AmazonEC2"}, false},
+	}
+	for _, c := range cases {
+		size, sum, err := verifyOfferFile(c.path, c.dl)
+		if (err == nil) != c.ok {
+			t.Errorf("%s as %s: expected ok=%v, got %v", c.path, c.dl.Filename, c.ok, err)
+		}
+		if err == nil && (size == 0 || len(sum) != 64) {
+			t.Errorf("%s: expected a size and hash, got %d and %q", c.path, size, sum)
+		}
+	}
+}
+
+func TestVerifyTruncatedOfferFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsprice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"index.json", "index.csv"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata/offers/v1.0/aws/AmazonEC2/current", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		// cut off mid-row, as an interrupted download would be
+		if err := ioutil.WriteFile(path, data[:len(data)*2/3], 0644); err != nil {
+			t.Fatal(err)
+		}
+		dl := offerDownload{Filename: "AmazonEC2" + filepath.Ext(name), OfferCode: "AmazonEC2"}
+		if _, _, err := verifyOfferFile(path, dl); err == nil {
+			t.Errorf("Expected a truncated %s to fail verification", name)
+		}
+	}
+}
+
+func TestFetchRejectsTruncatedOffer(t *testing.T) {
+	files := http.FileServer(http.Dir("testdata"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/offers/v1.0/aws/AmazonEC2/current/index.json" && r.Method == "GET" {
+			w.Write([]byte(`{"offerCode": "AmazonEC2", "products": {`))
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	client, cleanup := testClient(t, server.URL)
+	defer cleanup()
+
+	err := client.FetchJSON(context.Background())
+	failed, ok := err.(DownloadError)
+	if !ok {
+		t.Fatalf("Expected a DownloadError, got %v", err)
+	}
+	var parse *ParseError
+	if !errors.As(failed["AmazonEC2"], &parse) || len(failed) != 1 {
+		t.Errorf("Expected only AmazonEC2 to fail to parse, got %v", failed)
+	}
+	for _, name := range []string{"AmazonEC2.json", "AmazonEC2.json.part"} {
+		if _, err := os.Stat(client.path(name)); err == nil {
+			t.Errorf("Expected no %s in the cache", name)
+		}
+	}
+	entry, ok := client.loadManifest().entry("AmazonRDS.json")
+	if !ok || entry.Size == 0 || entry.SHA256 == "" {
+		t.Errorf("Expected the RDS size and hash in the manifest, got %+v", entry)
+	}
+}
+
+func TestReplaceFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsprice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "db")
+	if err := ioutil.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// a failed write leaves the old file in place
+	err = replaceFile(path, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errors.New("disk full")
+	})
+	if err == nil {
+		t.Error("Expected the write error")
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "old" {
+		t.Errorf("Expected the old file to survive, got %q", data)
+	}
+	if err := replaceFile(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "new" {
+		t.Errorf("Expected the new file, got %q", data)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp*")); len(matches) != 0 {
+		t.Errorf("Expected no temporary files left, got %v", matches)
+	}
+}
+
+func TestProcessRejectsCorruptedOffer(t *testing.T) {
+	client, cleanup := testClient(t, fixtureDir(t))
+	defer cleanup()
+	if err := client.FetchJSON(context.Background()); err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+
+	// flip a byte, keeping the size the manifest expects
+	path := client.path("AmazonEC2.json")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] ^= 1
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = client.ProcessJSON(context.Background())
+	var parse *ParseError
+	if !errors.As(err, &parse) || parse.File != "AmazonEC2.json" {
+		t.Fatalf("Expected a ParseError for AmazonEC2.json, got %v", err)
+	}
+	if _, ok := client.loadManifest().entry("AmazonEC2.json"); ok {
+		t.Error("Expected the corrupted file to be dropped from the manifest")
+	}
+
+	// so the next fetch replaces it
+	if err := client.FetchJSON(context.Background()); err != nil {
+		t.Fatalf("Error re-fetching: %v", err)
+	}
+	if err := client.ProcessJSON(context.Background()); err != nil {
+		t.Errorf("Error processing the re-fetched offer: %v", err)
+	}
+}